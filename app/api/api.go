@@ -73,6 +73,12 @@ func (api *Api) Handler() http.Handler {
 	// bits
 	r.Handle("/bits", api.handleBits())
 
+	// commerce
+	r.Handle("/commerce", api.handleCommerce())
+
+	// redemptions
+	r.Handle("/redemptions", api.handleRedemptions())
+
 	// shutdown
 	r.Handle("/shutdown", api.handleShutdown())
 
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	database "github.com/codephobia/twitch-eos-thanks/app/database"
+	twitch "github.com/codephobia/twitch-eos-thanks/app/twitch"
+)
+
+// CommerceResp is a purchase shown in the credits.
+type CommerceResp struct {
+	DisplayName     string `json:"display_name"`
+	ItemDescription string `json:"item_description"`
+	ItemImageURL    string `json:"item_image_url"`
+}
+
+// handleCommerce
+func (api *Api) handleCommerce() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleCommerceGet(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleCommerceGet
+func (api *Api) handleCommerceGet(w http.ResponseWriter, r *http.Request) {
+	// purchases to return
+	purchases := make([]*CommerceResp, 0)
+
+	// add headers to response
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// db commerce
+	dbCommerce := make([][]byte, 0)
+
+	// if limiting commerce to current stream
+	if api.config.ClientShowCurrentStream {
+		// get current stream commerce from db
+		err, c := api.database.GetAllSince(twitch.TWITCH_COMMERCE_DB_BUCKET, api.twitch.StreamStartTime, "timestamp")
+		if err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// set commerce
+		dbCommerce = c
+	} else {
+		// load all commerce from db
+		err, c := api.database.GetAll(twitch.TWITCH_COMMERCE_DB_BUCKET)
+		if err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// set commerce
+		dbCommerce = c
+	}
+
+	// unmarshal db commerce
+	for _, dbCommerceEvent := range dbCommerce {
+		var commerce database.Commerce
+		if err := json.Unmarshal(dbCommerceEvent, &commerce); err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// append to purchases returned
+		purchases = append(purchases, &CommerceResp{
+			DisplayName:     commerce.DisplayName,
+			ItemDescription: commerce.ItemDescription,
+			ItemImageURL:    commerce.ItemImageURL,
+		})
+	}
+
+	// encode the purchases
+	enc := json.NewEncoder(w)
+	enc.Encode(purchases)
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	database "github.com/codephobia/twitch-eos-thanks/app/database"
+	twitch "github.com/codephobia/twitch-eos-thanks/app/twitch"
+)
+
+// RedemptionResp is a reward redemption shown in the credits.
+type RedemptionResp struct {
+	DisplayName string `json:"display_name"`
+	RewardTitle string `json:"reward_title"`
+}
+
+// handleRedemptions
+func (api *Api) handleRedemptions() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleRedemptionsGet(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleRedemptionsGet
+func (api *Api) handleRedemptionsGet(w http.ResponseWriter, r *http.Request) {
+	// redemptions to return
+	redemptions := make([]*RedemptionResp, 0)
+
+	// add headers to response
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// db redemptions
+	dbRedemptions := make([][]byte, 0)
+
+	// if limiting redemptions to current stream
+	if api.config.ClientShowCurrentStream {
+		// get current stream redemptions from db
+		err, f := api.database.GetAllSince(twitch.TWITCH_REDEMPTION_DB_BUCKET, api.twitch.StreamStartTime, "timestamp")
+		if err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// set redemptions
+		dbRedemptions = f
+	} else {
+		// load all redemptions from db
+		err, f := api.database.GetAll(twitch.TWITCH_REDEMPTION_DB_BUCKET)
+		if err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// set redemptions
+		dbRedemptions = f
+	}
+
+	// rewards to thank people for, all rewards if none are configured
+	rewardIDs := make(map[string]bool)
+	for _, rewardID := range api.config.ClientRedemptionRewardIDs {
+		rewardIDs[rewardID] = true
+	}
+
+	// unmarshal db redemptions
+	for _, dbRedemption := range dbRedemptions {
+		var redemption database.Redemption
+		if err := json.Unmarshal(dbRedemption, &redemption); err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// skip rewards we aren't thanking people for
+		if len(rewardIDs) > 0 && !rewardIDs[redemption.RewardID] {
+			continue
+		}
+
+		// append to redemptions returned
+		redemptions = append(redemptions, &RedemptionResp{
+			DisplayName: redemption.DisplayName,
+			RewardTitle: redemption.RewardTitle,
+		})
+	}
+
+	// encode the redemptions
+	enc := json.NewEncoder(w)
+	enc.Encode(redemptions)
+}
@@ -11,6 +11,8 @@ type ApiSettings struct {
     ClientTimePer           int  `json:"clientTimePer"`
     ClientShowFollowers     bool `json:"clientShowFollowers"`
     ClientShowSubscribers   bool `json:"clientShowSubscribers"`
+    ClientShowPurchases     bool `json:"clientShowPurchases"`
+    ClientShowRedemptions   bool `json:"clientShowRedemptions"`
     ClientShowCurrentStream bool `json:"clientShowCurrentStream"`
 }
 
@@ -38,6 +40,8 @@ func (api *Api) handleSettingsGet(w http.ResponseWriter, r *http.Request) {
         ClientTimePer:           api.config.ClientTimePer,
         ClientShowFollowers:     api.config.ClientShowFollowers,
         ClientShowSubscribers:   api.config.ClientShowSubscribers,
+        ClientShowPurchases:     api.config.ClientShowPurchases,
+        ClientShowRedemptions:   api.config.ClientShowRedemptions,
         ClientShowCurrentStream: api.config.ClientShowCurrentStream,
     }
     
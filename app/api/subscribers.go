@@ -9,6 +9,16 @@ import (
 	twitch "github.com/codephobia/twitch-eos-thanks/app/twitch"
 )
 
+// SubscriberResp is a subscriber, or a bundle of gifted subs,
+// shown in the credits.
+type SubscriberResp struct {
+	DisplayName string `json:"display_name"`
+	SubPlan     string `json:"sub_plan"`
+	Months      int    `json:"months"`
+	Context     string `json:"context"`
+	GiftCount   int    `json:"gift_count"`
+}
+
 // handleSubscribers
 func (api *Api) handleSubscribers() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -24,14 +34,15 @@ func (api *Api) handleSubscribers() http.Handler {
 // handleSubscribersGet
 func (api *Api) handleSubscribersGet(w http.ResponseWriter, r *http.Request) {
 	// subscribers to return
-	subscribers := make([]database.Subscriber, 0)
+	subscribers := make([]*SubscriberResp, 0)
 
 	// add headers to response
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
-	// db subscribers
+	// db subscribers and gifts
 	dbSubscribers := make([][]byte, 0)
+	dbGifts := make([][]byte, 0)
 
 	// if limiting subscribers to current stream
 	if api.config.ClientShowCurrentStream {
@@ -45,6 +56,16 @@ func (api *Api) handleSubscribersGet(w http.ResponseWriter, r *http.Request) {
 
 		// set subscribers
 		dbSubscribers = f
+
+		// get current stream gifts from db
+		err, g := api.database.GetAllSince(twitch.TWITCH_GIFT_DB_BUCKET, api.twitch.StreamStartTime, "timestamp")
+		if err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// set gifts
+		dbGifts = g
 	} else {
 		// load all subscribers from db
 		err, f := api.database.GetAll(twitch.TWITCH_SUBSCRIBER_DB_BUCKET)
@@ -55,6 +76,39 @@ func (api *Api) handleSubscribersGet(w http.ResponseWriter, r *http.Request) {
 
 		// set subscribers
 		dbSubscribers = f
+
+		// load all gifts from db
+		err, g := api.database.GetAll(twitch.TWITCH_GIFT_DB_BUCKET)
+		if err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// set gifts
+		dbGifts = g
+	}
+
+	// thank the gifter once for the whole gift event, with the count
+	// from the server, since the recipients may not all be here
+	for _, dbGift := range dbGifts {
+		var gift database.Gift
+		if err := json.Unmarshal(dbGift, &gift); err != nil {
+			api.handleError(w, 500, err)
+			return
+		}
+
+		// recipients of gifts with no gifter name are thanked on their own
+		if len(gift.GifterName) == 0 {
+			continue
+		}
+
+		// append to subscribers returned
+		subscribers = append(subscribers, &SubscriberResp{
+			DisplayName: gift.GifterName,
+			SubPlan:     gift.SubPlan,
+			Context:     "subgift",
+			GiftCount:   gift.Count,
+		})
 	}
 
 	// unmarshal db subscribers
@@ -65,8 +119,29 @@ func (api *Api) handleSubscribersGet(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// gifted subs are thanked with their gift event
+		if subscriber.IsGift && len(subscriber.GifterName) > 0 {
+			if len(subscriber.GiftID) > 0 {
+				continue
+			}
+
+			// append the gifter of a sub with no gift event
+			subscribers = append(subscribers, &SubscriberResp{
+				DisplayName: subscriber.GifterName,
+				SubPlan:     subscriber.SubPlan,
+				Context:     subscriber.Context,
+				GiftCount:   1,
+			})
+			continue
+		}
+
 		// append to subscribers returned
-		subscribers = append(subscribers, subscriber)
+		subscribers = append(subscribers, &SubscriberResp{
+			DisplayName: subscriber.DisplayName,
+			SubPlan:     subscriber.SubPlan,
+			Months:      subscriber.Months,
+			Context:     subscriber.Context,
+		})
 	}
 
 	// encode the subscribers
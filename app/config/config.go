@@ -1,48 +1,63 @@
 package config
 
 import (
-    "encoding/json"
-    "fmt"
-    "os"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
 )
 
 var (
-    CONFIG_FILE string = "./config.json"
+	CONFIG_FILE string = "./config.json"
+
+	DEFAULT_TWITCH_API_URL string = "https://api.twitch.tv"
 )
 
 type Config struct {
-    TwitchClientID    string `json:"twitch_client_id"`
-    TwitchOAuthToken  string `json:"twitch_oauth_token"`
-    TwitchChannelID   string `json:"twitch_channel_id"`
-    DBFileName        string `json:"db_file_name"`
-    ApiHost           string `json:"api_host"`
-    ApiPort           string `json:"api_port"`
-    CodephobiaApiHost string `json:"codephobia_api_host"`
-    CodephobiaApiPort string `json:"codephobia_api_port"`
-    
-    ClientTimeTotal         int  `json:"client_time_total"`
-    ClientTimePer           int  `json:"client_time_per"`
-    ClientShowFollowers     bool `json:"client_show_followers"`
-    ClientShowSubscribers   bool `json:"client_show_subscribers"`
-    ClientShowCurrentStream bool `json:"client_show_current_stream"`
+	TwitchClientID    string `json:"twitch_client_id"`
+	TwitchOAuthToken  string `json:"twitch_oauth_token"`
+	TwitchChannelID   string `json:"twitch_channel_id"`
+	TwitchAPIURL      string `json:"twitch_api_url"`
+	DBFileName        string `json:"db_file_name"`
+	ApiHost           string `json:"api_host"`
+	ApiPort           string `json:"api_port"`
+	CodephobiaApiHost string `json:"codephobia_api_host"`
+	CodephobiaApiPort string `json:"codephobia_api_port"`
+
+	ClientTimeTotal         int  `json:"client_time_total"`
+	ClientTimePer           int  `json:"client_time_per"`
+	ClientShowFollowers     bool `json:"client_show_followers"`
+	ClientShowSubscribers   bool `json:"client_show_subscribers"`
+	ClientShowPurchases     bool `json:"client_show_purchases"`
+	ClientShowRedemptions   bool `json:"client_show_redemptions"`
+	ClientShowCurrentStream bool `json:"client_show_current_stream"`
+
+	// rewards to show redemptions of, all of them when empty
+	ClientRedemptionRewardIDs []string `json:"client_redemption_reward_ids"`
 }
 
 // create new config
 func NewConfig() *Config {
-    return &Config{}
+	return &Config{}
 }
 
 // load config file
 func (c *Config) Load() error {
-    configFile, err := os.Open(CONFIG_FILE)
-    if err != nil {
-        return fmt.Errorf("config open: %s", err)
-    }
-    defer configFile.Close()
-    
-    if err := json.NewDecoder(configFile).Decode(c); err != nil {
-        return fmt.Errorf("config decode: %s", err)
-    }
-    
-    return nil
-}
\ No newline at end of file
+	configFile, err := os.Open(CONFIG_FILE)
+	if err != nil {
+		return fmt.Errorf("config open: %s", err)
+	}
+	defer configFile.Close()
+
+	if err := json.NewDecoder(configFile).Decode(c); err != nil {
+		return fmt.Errorf("config decode: %s", err)
+	}
+
+	// default to the real twitch api
+	if len(c.TwitchAPIURL) == 0 {
+		c.TwitchAPIURL = DEFAULT_TWITCH_API_URL
+	}
+	c.TwitchAPIURL = strings.TrimSuffix(c.TwitchAPIURL, "/")
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package database
+
+import (
+	"time"
+)
+
+// Commerce is a commerce pub sub message from twitch.
+type Commerce struct {
+	ID              string           `json:"ID,omitempty"`
+	UserName        string           `json:"user_name"`
+	DisplayName     string           `json:"display_name"`
+	ChannelName     string           `json:"channel_name"`
+	UserID          string           `json:"user_id"`
+	ChannelID       string           `json:"channel_id"`
+	Time            time.Time        `json:"timestamp"`
+	ItemImageURL    string           `json:"item_image_url"`
+	ItemDescription string           `json:"item_description"`
+	SupportsChannel bool             `json:"supports_channel"`
+	PurchaseMessage *PurchaseMessage `json:"purchase_message"`
+}
+
+// PurchaseMessage is the message sent when a user makes a purchase.
+type PurchaseMessage struct {
+	Message string             `json:"message"`
+	Emotes  []*SubMessageEmote `json:"emotes"`
+}
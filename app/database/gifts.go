@@ -0,0 +1,19 @@
+package database
+
+import (
+	"time"
+)
+
+// Gift is a community gift of subs, counting every sub the gifter gave
+// away in it.
+type Gift struct {
+	ID            string    `json:"ID,omitempty"`
+	ChannelID     string    `json:"channel_id"`
+	GifterID      string    `json:"gifter_id"`
+	GifterName    string    `json:"gifter_name"`
+	IsAnonymous   bool      `json:"is_anonymous"`
+	SubPlan       string    `json:"sub_plan"`
+	Count         int       `json:"count"`
+	Timestamp     time.Time `json:"timestamp"`
+	LastTimestamp time.Time `json:"last_timestamp"`
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"time"
+)
+
+// Redemption is a channel points pub sub message from twitch.
+type Redemption struct {
+	ID           string    `json:"ID,omitempty"`
+	RedemptionID string    `json:"redemption_id"`
+	ChannelID    string    `json:"channel_id"`
+	UserID       string    `json:"user_id"`
+	UserName     string    `json:"user_name"`
+	DisplayName  string    `json:"display_name"`
+	Time         time.Time `json:"timestamp"`
+	RewardID     string    `json:"reward_id"`
+	RewardTitle  string    `json:"reward_title"`
+	RewardCost   int       `json:"reward_cost"`
+	UserInput    string    `json:"user_input"`
+	Status       string    `json:"status"`
+}
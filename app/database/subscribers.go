@@ -19,6 +19,15 @@ type Subscriber struct {
 	Months      int         `json:"months"`
 	Context     string      `json:"context"`
 	SubMessage  *SubMessage `json:"sub_message"`
+
+	IsGift             bool   `json:"is_gift"`
+	RecipientID        string `json:"recipient_id,omitempty"`
+	RecipientName      string `json:"recipient_name,omitempty"`
+	GifterID           string `json:"gifter_id,omitempty"`
+	GifterName         string `json:"gifter_name,omitempty"`
+	IsAnonymous        bool   `json:"is_anonymous"`
+	MultiMonthDuration int    `json:"multi_month_duration"`
+	GiftID             string `json:"gift_id,omitempty"`
 }
 
 // SubMessage is the message sent when a user subscribes.
@@ -0,0 +1,137 @@
+package twitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	database "github.com/codephobia/twitch-eos-thanks/app/database"
+)
+
+func (t *Twitch) getCommerce() error {
+	log.Printf("[INFO] getCommerce: checking api for commerce")
+
+	// get latest cached commerce time
+	latestCommerceTime, err := t.getLatestCommerceTime()
+	if err != nil {
+		return fmt.Errorf("latest commerce time: %s", err)
+	}
+
+	i := 0
+	loop := true
+
+	for loop {
+		offset := i * TWITCH_API_COMMERCE_LIMIT
+
+		// build out url
+		u := []string{
+			"http://",
+			t.config.CodephobiaApiHost,
+			":",
+			t.config.CodephobiaApiPort,
+			"/commerce?channelID=",
+			t.config.TwitchChannelID,
+			"&latest=",
+			strconv.FormatInt(latestCommerceTime.UnixNano(), 10),
+			"&limit=",
+			strconv.Itoa(TWITCH_API_COMMERCE_LIMIT),
+		}
+
+		// set offset if not on first page
+		if i > 0 {
+			u = append(u, strings.Join([]string{"&offset=", strconv.Itoa(offset)}, ""))
+		}
+		url := strings.Join(u, "")
+
+		// get commerce from server api
+		body, err := t.getApiResponse(url)
+		if err != nil {
+			return err
+		}
+
+		// decode body
+		commerceResp := &CommerceResp{}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(commerceResp); err != nil {
+			return fmt.Errorf("body decode: %s", err)
+		}
+
+		// update commerce
+		t.Commerce = append(t.Commerce, commerceResp.Data...)
+
+		// check if we need to keep looping
+		cnt := len(commerceResp.Data)
+		if cnt < TWITCH_API_COMMERCE_LIMIT {
+			// stop loop
+			loop = false
+		}
+
+		// increment loop
+		i++
+
+		// sleep so we don't hammer api
+		time.Sleep(TWITCH_API_DELAY)
+	}
+
+	log.Printf("[INFO] getCommerce: found [%d] new commerce", len(t.Commerce))
+
+	return nil
+}
+
+func (t *Twitch) getLatestCommerceTime() (time.Time, error) {
+	lt := time.Unix(0, 0)
+
+	// get commerce count
+	count, err := t.database.Count(TWITCH_COMMERCE_DB_BUCKET)
+	if err != nil {
+		return lt, fmt.Errorf("count: %s", err)
+	}
+
+	// if we have cached commerce, get latest commerce time
+	if count > 0 {
+		// get all commerce from database
+		err, dbCommerce := t.database.GetAll(TWITCH_COMMERCE_DB_BUCKET)
+		if err != nil {
+			return lt, fmt.Errorf("get commerce: %s", err)
+		}
+
+		// loop through commerce
+		for _, dbCommerceEvent := range dbCommerce {
+			// unmarshal commerce
+			var commerce database.Commerce
+			if err := json.Unmarshal(dbCommerceEvent, &commerce); err != nil {
+				return lt, fmt.Errorf("unmarshal commerce event: %s", err)
+			}
+
+			// check if commerce date is more recent
+			if commerce.Time.After(lt) {
+				lt = commerce.Time
+			}
+		}
+	}
+
+	return lt, nil
+}
+
+// save the commerce to the database
+func (t *Twitch) saveCommerce() error {
+	// check if we found commerce
+	if len(t.Commerce) == 0 {
+		return nil
+	}
+
+	for _, commerce := range t.Commerce {
+		// put the commerce data
+		if err := t.database.Put(TWITCH_COMMERCE_DB_BUCKET, commerce.ID, *commerce); err != nil {
+			return fmt.Errorf("saving commerce [%s]: %s", commerce.ID, err)
+		}
+	}
+
+	// reset the commerce
+	t.Commerce = make([]*database.Commerce, 0)
+
+	return nil
+}
@@ -2,6 +2,7 @@ package twitch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -132,7 +133,7 @@ func (t *Twitch) getLatestFollowerTime() (time.Time, error) {
 	return lt, nil
 }
 
-func (t *Twitch) getFollowerUserData() error {
+func (t *Twitch) getFollowerUserData(ctx context.Context) error {
 	// check if we found followers
 	if len(t.Followers) == 0 {
 		return nil
@@ -177,7 +178,7 @@ func (t *Twitch) getFollowerUserData() error {
 		url := strings.Join(u, "")
 
 		// get user data from twitch
-		body, err := t.getTwitchResponse(TwitchHelix, url)
+		body, err := t.getTwitchResponse(ctx, TwitchHelix, url)
 		if err != nil {
 			return err
 		}
@@ -210,9 +211,6 @@ func (t *Twitch) getFollowerUserData() error {
 
 		// increase i
 		i++
-
-		// sleep so we don't hammer twitch api
-		time.Sleep(TWITCH_API_DELAY)
 	}
 
 	return nil
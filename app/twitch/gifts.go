@@ -0,0 +1,137 @@
+package twitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	database "github.com/codephobia/twitch-eos-thanks/app/database"
+)
+
+func (t *Twitch) getGifts() error {
+	log.Printf("[INFO] getGifts: checking api for gifts")
+
+	// get latest cached gift time
+	latestGiftTime, err := t.getLatestGiftTime()
+	if err != nil {
+		return fmt.Errorf("latest gift time: %s", err)
+	}
+
+	i := 0
+	loop := true
+
+	for loop {
+		offset := i * TWITCH_API_GIFT_LIMIT
+
+		// build out url
+		u := []string{
+			"http://",
+			t.config.CodephobiaApiHost,
+			":",
+			t.config.CodephobiaApiPort,
+			"/gifts?channelID=",
+			t.config.TwitchChannelID,
+			"&latest=",
+			strconv.FormatInt(latestGiftTime.UnixNano(), 10),
+			"&limit=",
+			strconv.Itoa(TWITCH_API_GIFT_LIMIT),
+		}
+
+		// set offset if not on first page
+		if i > 0 {
+			u = append(u, strings.Join([]string{"&offset=", strconv.Itoa(offset)}, ""))
+		}
+		url := strings.Join(u, "")
+
+		// get gifts from server api
+		body, err := t.getApiResponse(url)
+		if err != nil {
+			return err
+		}
+
+		// decode body
+		giftsResp := &GiftsResp{}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(giftsResp); err != nil {
+			return fmt.Errorf("body decode: %s", err)
+		}
+
+		// update gifts
+		t.Gifts = append(t.Gifts, giftsResp.Data...)
+
+		// check if we need to keep looping
+		cnt := len(giftsResp.Data)
+		if cnt < TWITCH_API_GIFT_LIMIT {
+			// stop loop
+			loop = false
+		}
+
+		// increment loop
+		i++
+
+		// sleep so we don't hammer api
+		time.Sleep(TWITCH_API_DELAY)
+	}
+
+	log.Printf("[INFO] getGifts: found [%d] new gifts", len(t.Gifts))
+
+	return nil
+}
+
+func (t *Twitch) getLatestGiftTime() (time.Time, error) {
+	lt := time.Unix(0, 0)
+
+	// get gift count
+	count, err := t.database.Count(TWITCH_GIFT_DB_BUCKET)
+	if err != nil {
+		return lt, fmt.Errorf("count: %s", err)
+	}
+
+	// if we have cached gifts, get latest gift time
+	if count > 0 {
+		// get all gifts from database
+		err, dbGifts := t.database.GetAll(TWITCH_GIFT_DB_BUCKET)
+		if err != nil {
+			return lt, fmt.Errorf("get gifts: %s", err)
+		}
+
+		// loop through gifts
+		for _, dbGift := range dbGifts {
+			// unmarshal gift
+			var gift database.Gift
+			if err := json.Unmarshal(dbGift, &gift); err != nil {
+				return lt, fmt.Errorf("unmarshal gift event: %s", err)
+			}
+
+			// check if the last sub in the gift is more recent
+			if gift.LastTimestamp.After(lt) {
+				lt = gift.LastTimestamp
+			}
+		}
+	}
+
+	return lt, nil
+}
+
+// save the gifts to the database
+func (t *Twitch) saveGifts() error {
+	// check if we found gifts
+	if len(t.Gifts) == 0 {
+		return nil
+	}
+
+	for _, gift := range t.Gifts {
+		// put the gift data
+		if err := t.database.Put(TWITCH_GIFT_DB_BUCKET, gift.ID, *gift); err != nil {
+			return fmt.Errorf("saving gift [%s]: %s", gift.ID, err)
+		}
+	}
+
+	// reset the gifts
+	t.Gifts = make([]*database.Gift, 0)
+
+	return nil
+}
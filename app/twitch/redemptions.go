@@ -0,0 +1,137 @@
+package twitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	database "github.com/codephobia/twitch-eos-thanks/app/database"
+)
+
+func (t *Twitch) getRedemptions() error {
+	log.Printf("[INFO] getRedemptions: checking api for redemptions")
+
+	// get latest cached redemption time
+	latestRedemptionTime, err := t.getLatestRedemptionTime()
+	if err != nil {
+		return fmt.Errorf("latest redemption time: %s", err)
+	}
+
+	i := 0
+	loop := true
+
+	for loop {
+		offset := i * TWITCH_API_REDEMPTION_LIMIT
+
+		// build out url
+		u := []string{
+			"http://",
+			t.config.CodephobiaApiHost,
+			":",
+			t.config.CodephobiaApiPort,
+			"/redemptions?channelID=",
+			t.config.TwitchChannelID,
+			"&latest=",
+			strconv.FormatInt(latestRedemptionTime.UnixNano(), 10),
+			"&limit=",
+			strconv.Itoa(TWITCH_API_REDEMPTION_LIMIT),
+		}
+
+		// set offset if not on first page
+		if i > 0 {
+			u = append(u, strings.Join([]string{"&offset=", strconv.Itoa(offset)}, ""))
+		}
+		url := strings.Join(u, "")
+
+		// get redemptions from server api
+		body, err := t.getApiResponse(url)
+		if err != nil {
+			return err
+		}
+
+		// decode body
+		redemptionsResp := &RedemptionsResp{}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(redemptionsResp); err != nil {
+			return fmt.Errorf("body decode: %s", err)
+		}
+
+		// update redemptions
+		t.Redemptions = append(t.Redemptions, redemptionsResp.Data...)
+
+		// check if we need to keep looping
+		cnt := len(redemptionsResp.Data)
+		if cnt < TWITCH_API_REDEMPTION_LIMIT {
+			// stop loop
+			loop = false
+		}
+
+		// increment loop
+		i++
+
+		// sleep so we don't hammer api
+		time.Sleep(TWITCH_API_DELAY)
+	}
+
+	log.Printf("[INFO] getRedemptions: found [%d] new redemptions", len(t.Redemptions))
+
+	return nil
+}
+
+func (t *Twitch) getLatestRedemptionTime() (time.Time, error) {
+	lt := time.Unix(0, 0)
+
+	// get redemption count
+	count, err := t.database.Count(TWITCH_REDEMPTION_DB_BUCKET)
+	if err != nil {
+		return lt, fmt.Errorf("count: %s", err)
+	}
+
+	// if we have cached redemptions, get latest redemption time
+	if count > 0 {
+		// get all redemptions from database
+		err, dbRedemptions := t.database.GetAll(TWITCH_REDEMPTION_DB_BUCKET)
+		if err != nil {
+			return lt, fmt.Errorf("get redemptions: %s", err)
+		}
+
+		// loop through redemptions
+		for _, dbRedemption := range dbRedemptions {
+			// unmarshal redemption
+			var redemption database.Redemption
+			if err := json.Unmarshal(dbRedemption, &redemption); err != nil {
+				return lt, fmt.Errorf("unmarshal redemption event: %s", err)
+			}
+
+			// check if redemption date is more recent
+			if redemption.Time.After(lt) {
+				lt = redemption.Time
+			}
+		}
+	}
+
+	return lt, nil
+}
+
+// save the redemptions to the database
+func (t *Twitch) saveRedemptions() error {
+	// check if we found redemptions
+	if len(t.Redemptions) == 0 {
+		return nil
+	}
+
+	for _, redemption := range t.Redemptions {
+		// put the redemption data
+		if err := t.database.Put(TWITCH_REDEMPTION_DB_BUCKET, redemption.ID, *redemption); err != nil {
+			return fmt.Errorf("saving redemption [%s]: %s", redemption.ID, err)
+		}
+	}
+
+	// reset the redemptions
+	t.Redemptions = make([]*database.Redemption, 0)
+
+	return nil
+}
@@ -20,6 +20,21 @@ type BitsResp struct {
 	Data []*database.Bit `json:"data"`
 }
 
+// commerce list response
+type CommerceResp struct {
+	Data []*database.Commerce `json:"data"`
+}
+
+// gift list response
+type GiftsResp struct {
+	Data []*database.Gift `json:"data"`
+}
+
+// redemption list response
+type RedemptionsResp struct {
+	Data []*database.Redemption `json:"data"`
+}
+
 // twitch user response
 type UserResp struct {
 	Data []*TwitchUser `json:"data"`
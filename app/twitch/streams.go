@@ -2,6 +2,7 @@ package twitch
 
 import (
     "bytes"
+    "context"
     "encoding/json"
     "fmt"
     "strings"
@@ -13,7 +14,7 @@ var (
 )
 
 // get the time of the current stream start
-func (t *Twitch) getStreamStart() (time.Time, error) {
+func (t *Twitch) getStreamStart(ctx context.Context) (time.Time, error) {
     // make default time
     tm := time.Unix(0, 0)
     
@@ -22,7 +23,7 @@ func (t *Twitch) getStreamStart() (time.Time, error) {
     url := strings.Join(u, "")
 
     // get current stream
-    body, err := t.getTwitchResponse(TwitchV5, url)
+    body, err := t.getTwitchResponse(ctx, TwitchV5, url)
     if err != nil {
         return tm, err
     }
@@ -1,10 +1,13 @@
 package twitch
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/codephobia/twitch-eos-thanks/helix"
+
 	config "github.com/codephobia/twitch-eos-thanks/app/config"
 	database "github.com/codephobia/twitch-eos-thanks/app/database"
 	util "github.com/codephobia/twitch-eos-thanks/app/util"
@@ -16,6 +19,9 @@ var (
 	TWITCH_API_FOLLOWER_LIMIT   int           = 100
 	TWITCH_API_SUBSCRIBER_LIMIT int           = 100
 	TWITCH_API_BITS_LIMIT       int           = 100
+	TWITCH_API_COMMERCE_LIMIT   int           = 100
+	TWITCH_API_REDEMPTION_LIMIT int           = 100
+	TWITCH_API_GIFT_LIMIT       int           = 100
 	TWITCH_API_USER_LIMIT       int           = 100
 
 	TWITCH_HELIX_USERS_URL string = "/users?"
@@ -24,6 +30,9 @@ var (
 	TWITCH_FOLLOWER_DB_BUCKET   []string = append(TWITCH_DB_BUCKET, "followers")
 	TWITCH_SUBSCRIBER_DB_BUCKET []string = append(TWITCH_DB_BUCKET, "subscribers")
 	TWITCH_BIT_DB_BUCKET        []string = append(TWITCH_DB_BUCKET, "bits")
+	TWITCH_COMMERCE_DB_BUCKET   []string = append(TWITCH_DB_BUCKET, "commerce")
+	TWITCH_REDEMPTION_DB_BUCKET []string = append(TWITCH_DB_BUCKET, "redemptions")
+	TWITCH_GIFT_DB_BUCKET       []string = append(TWITCH_DB_BUCKET, "gifts")
 )
 
 // twitch
@@ -31,10 +40,14 @@ type Twitch struct {
 	config   *config.Config
 	database *database.Database
 	timer    *util.Timer
+	helix    *helix.Client
 
 	Followers       []*Follower
 	Subscribers     []*database.Subscriber
 	Bits            []*database.Bit
+	Commerce        []*database.Commerce
+	Redemptions     []*database.Redemption
+	Gifts           []*database.Gift
 	StreamStartTime time.Time
 }
 
@@ -55,17 +68,38 @@ func NewTwitch(c *config.Config, db *database.Database) (*Twitch, error) {
 		return nil, fmt.Errorf("init twitch bits bucket: %s", err)
 	}
 
+	// init the commerce bucket
+	if err := db.InitBucket(TWITCH_COMMERCE_DB_BUCKET); err != nil {
+		return nil, fmt.Errorf("init twitch commerce bucket: %s", err)
+	}
+
+	// init the redemptions bucket
+	if err := db.InitBucket(TWITCH_REDEMPTION_DB_BUCKET); err != nil {
+		return nil, fmt.Errorf("init twitch redemptions bucket: %s", err)
+	}
+
+	// init the gifts bucket
+	if err := db.InitBucket(TWITCH_GIFT_DB_BUCKET); err != nil {
+		return nil, fmt.Errorf("init twitch gifts bucket: %s", err)
+	}
+
+	// point at the configured twitch api, which may be a local fake
+	twitchAPIURL = c.TwitchAPIURL
+
 	// return new twitch struct
 	return &Twitch{
 		config:   c,
 		database: db,
+		helix:    helix.NewClient(c.TwitchClientID, helix.StaticToken(c.TwitchOAuthToken)),
 	}, nil
 }
 
 // get data from helix
 func (t *Twitch) Get() error {
+	ctx := context.Background()
+
 	// get stream start time
-	if streamTime, err := t.getStreamStart(); err != nil {
+	if streamTime, err := t.getStreamStart(ctx); err != nil {
 		return err
 	} else {
 		t.StreamStartTime = streamTime
@@ -77,7 +111,7 @@ func (t *Twitch) Get() error {
 	}
 
 	// get user data from follower ids
-	if err := t.getFollowerUserData(); err != nil {
+	if err := t.getFollowerUserData(ctx); err != nil {
 		return err
 	}
 
@@ -96,6 +130,16 @@ func (t *Twitch) Get() error {
 		return err
 	}
 
+	// get gifts
+	if err := t.getGifts(); err != nil {
+		return err
+	}
+
+	// save the gifts to the database
+	if err := t.saveGifts(); err != nil {
+		return err
+	}
+
 	// get bits
 	if err := t.getBits(); err != nil {
 		return err
@@ -106,6 +150,26 @@ func (t *Twitch) Get() error {
 		return err
 	}
 
+	// get commerce
+	if err := t.getCommerce(); err != nil {
+		return err
+	}
+
+	// save the commerce to the database
+	if err := t.saveCommerce(); err != nil {
+		return err
+	}
+
+	// get redemptions
+	if err := t.getRedemptions(); err != nil {
+		return err
+	}
+
+	// save the redemptions to the database
+	if err := t.saveRedemptions(); err != nil {
+		return err
+	}
+
 	// run timer to poll twitch api
 	t.startTimer()
 
@@ -8,10 +8,13 @@ const (
 )
 
 func (v TwitchVersion) Url() string {
-    return twitchVersionUrl[v]
+    return twitchAPIURL + twitchVersionUrl[v]
 }
 
+// base url of the api, overridden by the config
+var twitchAPIURL = "https://api.twitch.tv"
+
 var twitchVersionUrl = map[TwitchVersion]string{
-    TwitchV5:    "https://api.twitch.tv/kraken",
-    TwitchHelix: "https://api.twitch.tv/helix",
+    TwitchV5:    "/kraken",
+    TwitchHelix: "/helix",
 }
\ No newline at end of file
@@ -87,20 +87,56 @@ function init() {
                 waterfallCb(err);
             });
         },
-    ], function (err, settings, followers, bits, subscribers) {
+        function (settings, followers, bits, subscribers, waterfallCb) {
+            // check if we are showing purchases
+            if (!settings.clientShowPurchases) {
+                waterfallCb(null, settings, followers, bits, subscribers, []);
+                return;
+            }
+            
+            // get purchases
+            $.ajax({
+                url: host + "/commerce"
+            })
+            .done(function (purchases) {
+                waterfallCb(null, settings, followers, bits, subscribers, purchases);
+            })
+            .fail(function (err) {
+                waterfallCb(err);
+            });
+        },
+        function (settings, followers, bits, subscribers, purchases, waterfallCb) {
+            // check if we are showing redemptions
+            if (!settings.clientShowRedemptions) {
+                waterfallCb(null, settings, followers, bits, subscribers, purchases, []);
+                return;
+            }
+            
+            // get redemptions
+            $.ajax({
+                url: host + "/redemptions"
+            })
+            .done(function (redemptions) {
+                waterfallCb(null, settings, followers, bits, subscribers, purchases, redemptions);
+            })
+            .fail(function (err) {
+                waterfallCb(err);
+            });
+        },
+    ], function (err, settings, followers, bits, subscribers, purchases, redemptions) {
         if (err) {
             console.error(err);
             finish(0);
         } else {
-            start(settings, followers, bits, subscribers);
+            start(settings, followers, bits, subscribers, purchases, redemptions);
         }
     });
 }
 
 // start showing followers
-function start(settings, followers, bits, subscribers) {
+function start(settings, followers, bits, subscribers, purchases, redemptions) {
     // number of users
-    var count = followers.length + bits.length + subscribers.length;
+    var count = followers.length + bits.length + subscribers.length + purchases.length + redemptions.length;
     
     // time length of outro in milliseconds
     var time = (count) ? settings.clientTimeTotal : 0;
@@ -159,6 +195,36 @@ function start(settings, followers, bits, subscribers) {
 
         userCount++;
     }
+
+    // loop through purchases
+    for (var i = 0; i < purchases.length; i++) {
+        // time to show this
+        var t = Math.floor(interval * userCount);
+        
+        // set timeout for showing
+        (function (i, t, userCount) {
+            setTimeout(function () {
+                showUser(purchases[i], userCount, 'purchased');
+            }, t + 1000);
+        })(i, t, userCount);
+
+        userCount++;
+    }
+
+    // loop through redemptions
+    for (var i = 0; i < redemptions.length; i++) {
+        // time to show this
+        var t = Math.floor(interval * userCount);
+        
+        // set timeout for showing
+        (function (i, t, userCount) {
+            setTimeout(function () {
+                showUser(redemptions[i], userCount, 'redeemed');
+            }, t + 1000);
+        })(i, t, userCount);
+
+        userCount++;
+    }
     
     finish(time + 3000);
 }
@@ -196,6 +262,12 @@ function showUser(user, userCount, actionType) {
             } else {
                 actionEl.addClass('bit1');
             }
+        } else if (actionType === 'subscribed' && user.gift_count > 0) {
+            actionEl = $("<h2>").addClass("action subscribed gifted").html("gifted");
+            
+            // handle gifted sub count
+            var giftsEl = $("<div>").addClass("times").html(user.gift_count + ((user.gift_count > 1) ? " subs" : " sub"));
+            actionEl.append(giftsEl);
         } else if (actionType === 'subscribed') {
             actionEl = $("<h2>").addClass("action subscribed").html("subscribed");
             
@@ -209,6 +281,21 @@ function showUser(user, userCount, actionType) {
                 var timesEl = $("<div>").addClass("times").html("x" + user.months);
                 actionEl.append(timesEl);
             }
+        } else if (actionType === 'purchased') {
+            actionEl = $("<h2>").addClass("action purchased").html("purchased");
+            
+            // handle purchased item
+            var itemEl = $("<div>").addClass("item").text(user.item_description);
+            if (user.item_image_url) {
+                itemEl.prepend($("<img>").attr("src", user.item_image_url).attr("alt", ""));
+            }
+            actionEl.append(itemEl);
+        } else if (actionType === 'redeemed') {
+            actionEl = $("<h2>").addClass("action redeemed").html("redeemed");
+            
+            // handle reward title
+            var rewardEl = $("<div>").addClass("reward").text(user.reward_title);
+            actionEl.append(rewardEl);
         }
         
         userEl.append(usernameEl);
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/codephobia/twitch-eos-thanks/faketwitch"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9000", "address to serve the fake twitch on")
+	fixtures := flag.String("fixtures", "", "json fixtures file to seed the fake with")
+	flag.Parse()
+
+	// create the fake
+	fake := faketwitch.NewServer()
+	if len(*fixtures) > 0 {
+		if err := fake.LoadFixtures(*fixtures); err != nil {
+			log.Fatalf("[ERROR] faketwitch: fixtures: %s", err)
+		}
+	}
+
+	// run server
+	log.Printf("[INFO] faketwitch: running: %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, fake))
+}
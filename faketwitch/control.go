@@ -0,0 +1,135 @@
+package faketwitch
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// fakeMessageReq is a request to publish a pub sub message.
+type fakeMessageReq struct {
+	Topic string `json:"topic"`
+	// message as twitch sends it, either a json string or
+	// an object that's encoded in to one
+	Message json.RawMessage `json:"message"`
+}
+
+// fakeListenErrorReq is a request to script a LISTEN error.
+type fakeListenErrorReq struct {
+	Topic string `json:"topic"`
+	Error string `json:"error"`
+}
+
+// fakePongsReq is a request to drop or answer PINGs.
+type fakePongsReq struct {
+	Drop bool `json:"drop"`
+}
+
+// handles publishing a message to pub sub listeners
+func (s *Server) handleFakeMessage() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fakeMessageReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// pub sub messages carry their payload as a string
+		message := string(req.Message)
+		var str string
+		if err := json.Unmarshal(req.Message, &str); err == nil {
+			message = str
+		}
+
+		sent := s.Publish(req.Topic, message)
+
+		writeJSON(w, http.StatusOK, map[string]int{
+			"sent": sent,
+		})
+	})
+}
+
+// handles sending a RECONNECT to every connection
+func (s *Server) handleFakeReconnect() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Reconnect()
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handles dropping every connection
+func (s *Server) handleFakeDisconnect() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Disconnect()
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handles scripting a LISTEN error for a topic
+func (s *Server) handleFakeListenError() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fakeListenErrorReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.SetListenError(req.Topic, req.Error)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handles dropping or answering PINGs
+func (s *Server) handleFakePongs() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req fakePongsReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.DropPongs(req.Drop)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handles seeding more fixtures in to a running fake
+func (s *Server) handleFakeFixtures() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var fixtures Fixtures
+		if err := json.NewDecoder(r.Body).Decode(&fixtures); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		s.Load(&fixtures)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handles removing a follow, ?from_id=&to_id=
+func (s *Server) handleFakeRemoveFollow() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		s.RemoveFollow(query.Get("from_id"), query.Get("to_id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handles ending a subscription, ?broadcaster_id=&user_id=
+func (s *Server) handleFakeRemoveSubscription() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		s.RemoveSubscription(query.Get("broadcaster_id"), query.Get("user_id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handles revoking an access token, ?access_token=
+func (s *Server) handleFakeRevokeToken() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.RevokeToken(r.URL.Query().Get("access_token"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
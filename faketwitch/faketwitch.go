@@ -0,0 +1,346 @@
+// Package faketwitch is a local stand in for the parts of twitch used by
+// the server and app: the pub sub websocket, the oauth token endpoints and
+// the helix / kraken users, follows, streams, subscriptions and bits
+// leaderboard endpoints.
+//
+// Everything is served from one handler, so with a fake at
+// http://localhost:9000 the server is pointed at it with:
+//
+//	"twitch_api_url": "http://localhost:9000",
+//	"twitch_oauth_url": "http://localhost:9000/oauth2",
+//	"twitch_pubsub_url": "ws://localhost:9000/pubsub"
+//
+// and the app with "twitch_api_url": "http://localhost:9000".
+package faketwitch
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// User is a twitch user.
+type User struct {
+	ID              string `json:"id"`
+	Login           string `json:"login"`
+	DisplayName     string `json:"display_name"`
+	ProfileImageURL string `json:"profile_image_url"`
+}
+
+// Follow is a user following a channel.
+type Follow struct {
+	FromID     string    `json:"from_id"`
+	ToID       string    `json:"to_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// Stream is a live stream on a channel.
+type Stream struct {
+	UserID    string    `json:"user_id"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Subscription is a user subscribed to a channel.
+type Subscription struct {
+	BroadcasterID string `json:"broadcaster_id"`
+	UserID        string `json:"user_id"`
+	Tier          string `json:"tier"`
+	IsGift        bool   `json:"is_gift"`
+	GifterID      string `json:"gifter_id"`
+}
+
+// Cheer is bits cheered to a channel, adding to the bits leaderboard.
+type Cheer struct {
+	BroadcasterID string `json:"broadcaster_id"`
+	UserID        string `json:"user_id"`
+	Bits          int    `json:"bits"`
+}
+
+// Token is an access token, with its refresh token if it has one.
+// Tokens without a user id are app access tokens.
+type Token struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	UserID       string   `json:"user_id"`
+	Scopes       []string `json:"scopes"`
+}
+
+// Fixtures is the data a fake is seeded with.
+type Fixtures struct {
+	Users   []*User   `json:"users"`
+	Follows []*Follow `json:"follows"`
+	Streams []*Stream `json:"streams"`
+	Tokens  []*Token  `json:"tokens"`
+
+	Subscriptions []*Subscription `json:"subscriptions"`
+	Cheers        []*Cheer        `json:"cheers"`
+	// authorization codes, exchanged for their token
+	Codes map[string]*Token `json:"codes"`
+}
+
+// Server is a fake twitch.
+type Server struct {
+	// client id handed out on validate responses
+	ClientID string
+
+	mu      sync.Mutex
+	users   map[string]*User
+	follows []*Follow
+	streams map[string]*Stream
+	tokens  map[string]*Token
+	refresh map[string]*Token
+	codes   map[string]*Token
+
+	// subscriptions and bits leaderboard scores, by broadcaster then user
+	subscriptions map[string]map[string]*Subscription
+	bits          map[string]map[string]int
+
+	pubsub *pubsub
+
+	router *mux.Router
+}
+
+// NewServer returns a new fake twitch with no data.
+func NewServer() *Server {
+	s := &Server{
+		ClientID: "faketwitch",
+
+		users:   make(map[string]*User),
+		streams: make(map[string]*Stream),
+		tokens:  make(map[string]*Token),
+		refresh: make(map[string]*Token),
+		codes:   make(map[string]*Token),
+
+		subscriptions: make(map[string]map[string]*Subscription),
+		bits:          make(map[string]map[string]int),
+
+		pubsub: newPubSub(),
+	}
+
+	s.router = s.routes()
+
+	return s
+}
+
+// LoadFixtures seeds the fake from a json fixtures file.
+func (s *Server) LoadFixtures(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var fixtures Fixtures
+	if err := json.NewDecoder(f).Decode(&fixtures); err != nil {
+		return err
+	}
+
+	s.Load(&fixtures)
+
+	return nil
+}
+
+// Load seeds the fake with fixtures.
+func (s *Server) Load(f *Fixtures) {
+	for _, user := range f.Users {
+		s.AddUser(user)
+	}
+	for _, follow := range f.Follows {
+		s.AddFollow(follow)
+	}
+	for _, stream := range f.Streams {
+		s.SetStream(stream)
+	}
+	for _, token := range f.Tokens {
+		s.AddToken(token)
+	}
+	for code, token := range f.Codes {
+		s.AddCode(code, token)
+	}
+	for _, subscription := range f.Subscriptions {
+		s.AddSubscription(subscription)
+	}
+	for _, cheer := range f.Cheers {
+		s.AddCheer(cheer)
+	}
+}
+
+// AddUser adds or replaces a user.
+func (s *Server) AddUser(user *User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[user.ID] = user
+}
+
+// AddFollow adds a follow to a channel.
+func (s *Server) AddFollow(follow *Follow) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if follow.FollowedAt.IsZero() {
+		follow.FollowedAt = time.Now().UTC()
+	}
+
+	s.follows = append(s.follows, follow)
+}
+
+// RemoveFollow removes a user's follow of a channel.
+func (s *Server) RemoveFollow(fromID, toID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	follows := s.follows[:0]
+	for _, follow := range s.follows {
+		if follow.FromID != fromID || follow.ToID != toID {
+			follows = append(follows, follow)
+		}
+	}
+	s.follows = follows
+}
+
+// SetStream puts a channel live.
+func (s *Server) SetStream(stream *Stream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stream.StartedAt.IsZero() {
+		stream.StartedAt = time.Now().UTC()
+	}
+
+	s.streams[stream.UserID] = stream
+}
+
+// EndStream takes a channel offline.
+func (s *Server) EndStream(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.streams, userID)
+}
+
+// AddSubscription adds or replaces a user's subscription to a channel.
+func (s *Server) AddSubscription(subscription *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(subscription.Tier) == 0 {
+		subscription.Tier = "1000"
+	}
+
+	subs, ok := s.subscriptions[subscription.BroadcasterID]
+	if !ok {
+		subs = make(map[string]*Subscription)
+		s.subscriptions[subscription.BroadcasterID] = subs
+	}
+	subs[subscription.UserID] = subscription
+}
+
+// RemoveSubscription ends a user's subscription to a channel.
+func (s *Server) RemoveSubscription(broadcasterID string, userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscriptions[broadcasterID], userID)
+}
+
+// AddCheer adds cheered bits to a user's leaderboard score.
+func (s *Server) AddCheer(cheer *Cheer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scores, ok := s.bits[cheer.BroadcasterID]
+	if !ok {
+		scores = make(map[string]int)
+		s.bits[cheer.BroadcasterID] = scores
+	}
+	scores[cheer.UserID] += cheer.Bits
+}
+
+// AddToken adds an access token the fake accepts.
+func (s *Server) AddToken(token *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token.AccessToken] = token
+	if len(token.RefreshToken) > 0 {
+		s.refresh[token.RefreshToken] = token
+	}
+}
+
+// RevokeToken stops the fake accepting an access token.
+func (s *Server) RevokeToken(accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tokens, accessToken)
+}
+
+// AddCode adds an authorization code that exchanges for a token
+// for the given user and scopes.
+func (s *Server) AddCode(code string, token *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.codes[code] = token
+}
+
+// ServeHTTP serves the fake twitch endpoints.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// creates the router for the fake endpoints
+func (s *Server) routes() *mux.Router {
+	r := mux.NewRouter()
+
+	// pub sub websocket
+	r.Handle("/pubsub", s.handlePubSub())
+
+	// oauth
+	r.Handle("/oauth2/token", s.handleToken()).Methods("POST")
+	r.Handle("/oauth2/validate", s.handleValidate()).Methods("GET")
+
+	// helix
+	r.Handle("/helix/users", s.authorized(s.handleUsers())).Methods("GET")
+	r.Handle("/helix/users/follows", s.authorized(s.handleFollows())).Methods("GET")
+	r.Handle("/helix/streams", s.authorized(s.handleStreams())).Methods("GET")
+	r.Handle("/helix/subscriptions", s.authorized(s.handleSubscriptions())).Methods("GET")
+	r.Handle("/helix/bits/leaderboard", s.authorized(s.handleBitsLeaderboard())).Methods("GET")
+
+	// kraken
+	r.Handle("/kraken/streams/{channelID}", s.authorized(s.handleKrakenStream())).Methods("GET")
+
+	// scripting a running fake
+	r.Handle("/fake/pubsub/message", s.handleFakeMessage()).Methods("POST")
+	r.Handle("/fake/pubsub/reconnect", s.handleFakeReconnect()).Methods("POST")
+	r.Handle("/fake/pubsub/disconnect", s.handleFakeDisconnect()).Methods("POST")
+	r.Handle("/fake/pubsub/errors", s.handleFakeListenError()).Methods("POST")
+	r.Handle("/fake/pubsub/pongs", s.handleFakePongs()).Methods("POST")
+	r.Handle("/fake/fixtures", s.handleFakeFixtures()).Methods("POST")
+	r.Handle("/fake/follows", s.handleFakeRemoveFollow()).Methods("DELETE")
+	r.Handle("/fake/subscriptions", s.handleFakeRemoveSubscription()).Methods("DELETE")
+	r.Handle("/fake/tokens", s.handleFakeRevokeToken()).Methods("DELETE")
+
+	return r
+}
+
+// writes a json response
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+// writes a twitch style json error
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error":   http.StatusText(status),
+		"status":  status,
+		"message": message,
+	})
+}
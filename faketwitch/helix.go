@@ -0,0 +1,404 @@
+package faketwitch
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	// rate limit bucket reported on every helix response
+	helixRateLimit = 800
+
+	helixFollowsDefault = 20
+	helixFollowsMax     = 100
+
+	helixSubscriptionsDefault = 20
+	helixSubscriptionsMax     = 100
+
+	helixLeaderboardDefault = 10
+	helixLeaderboardMax     = 100
+)
+
+// followResp is a follow on a helix follows page.
+type followResp struct {
+	FromID     string `json:"from_id"`
+	FromLogin  string `json:"from_login"`
+	FromName   string `json:"from_name"`
+	ToID       string `json:"to_id"`
+	FollowedAt string `json:"followed_at"`
+}
+
+// followsResp is a page of helix follows.
+type followsResp struct {
+	Total      int           `json:"total"`
+	Data       []*followResp `json:"data"`
+	Pagination struct {
+		Cursor string `json:"cursor,omitempty"`
+	} `json:"pagination"`
+}
+
+// streamResp is a helix stream.
+type streamResp struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Type      string `json:"type"`
+	StartedAt string `json:"started_at"`
+}
+
+// subscriptionResp is a subscription on a helix subscriptions page.
+type subscriptionResp struct {
+	BroadcasterID string `json:"broadcaster_id"`
+	GifterID      string `json:"gifter_id"`
+	GifterLogin   string `json:"gifter_login"`
+	GifterName    string `json:"gifter_name"`
+	IsGift        bool   `json:"is_gift"`
+	Tier          string `json:"tier"`
+	PlanName      string `json:"plan_name"`
+	UserID        string `json:"user_id"`
+	UserLogin     string `json:"user_login"`
+	UserName      string `json:"user_name"`
+}
+
+// subscriptionsResp is a page of helix subscriptions.
+type subscriptionsResp struct {
+	Total      int                 `json:"total"`
+	Data       []*subscriptionResp `json:"data"`
+	Pagination struct {
+		Cursor string `json:"cursor,omitempty"`
+	} `json:"pagination"`
+}
+
+// leaderboardResp is a user on the helix bits leaderboard.
+type leaderboardResp struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+	Rank      int    `json:"rank"`
+	Score     int    `json:"score"`
+}
+
+// handles helix users lookups by id and login
+func (s *Server) handleUsers() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		query := r.URL.Query()
+		users := make([]*User, 0)
+
+		// users by id
+		for _, id := range query["id"] {
+			if user, ok := s.users[id]; ok {
+				users = append(users, user)
+			}
+		}
+
+		// users by login
+		for _, login := range query["login"] {
+			for _, user := range s.users {
+				if user.Login == login {
+					users = append(users, user)
+				}
+			}
+		}
+
+		writeHelix(w, map[string]interface{}{
+			"data": users,
+		})
+	})
+}
+
+// handles pages of helix follows, newest first
+func (s *Server) handleFollows() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		query := r.URL.Query()
+		toID := query.Get("to_id")
+		fromID := query.Get("from_id")
+
+		// page size
+		first := helixFollowsDefault
+		if len(query.Get("first")) > 0 {
+			n, err := strconv.Atoi(query.Get("first"))
+			if err != nil || n < 1 || n > helixFollowsMax {
+				writeError(w, http.StatusBadRequest, "Invalid first parameter")
+				return
+			}
+			first = n
+		}
+
+		// page offset from the cursor
+		offset := 0
+		if cursor := query.Get("after"); len(cursor) > 0 {
+			n, err := decodeCursor(cursor)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			offset = n
+		}
+
+		// matching follows, newest first
+		matches := make([]*Follow, 0)
+		for i := len(s.follows) - 1; i >= 0; i-- {
+			follow := s.follows[i]
+			if len(toID) > 0 && follow.ToID != toID {
+				continue
+			}
+			if len(fromID) > 0 && follow.FromID != fromID {
+				continue
+			}
+			matches = append(matches, follow)
+		}
+
+		resp := &followsResp{
+			Total: len(matches),
+			Data:  make([]*followResp, 0),
+		}
+
+		// build the page
+		for i := offset; i < len(matches) && i < offset+first; i++ {
+			follow := matches[i]
+
+			data := &followResp{
+				FromID:     follow.FromID,
+				ToID:       follow.ToID,
+				FollowedAt: follow.FollowedAt.UTC().Format(time.RFC3339),
+			}
+			if user, ok := s.users[follow.FromID]; ok {
+				data.FromLogin = user.Login
+				data.FromName = user.DisplayName
+			}
+
+			resp.Data = append(resp.Data, data)
+		}
+
+		// only hand out a cursor when there's another page
+		if offset+first < len(matches) {
+			resp.Pagination.Cursor = encodeCursor(offset + first)
+		}
+
+		writeHelix(w, resp)
+	})
+}
+
+// handles helix live stream lookups by user id
+func (s *Server) handleStreams() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		streams := make([]*streamResp, 0)
+		for _, userID := range r.URL.Query()["user_id"] {
+			if stream, ok := s.streams[userID]; ok {
+				streams = append(streams, &streamResp{
+					ID:        "stream-" + stream.UserID,
+					UserID:    stream.UserID,
+					Type:      "live",
+					StartedAt: stream.StartedAt.UTC().Format(time.RFC3339),
+				})
+			}
+		}
+
+		writeHelix(w, map[string]interface{}{
+			"data": streams,
+		})
+	})
+}
+
+// handles the kraken live stream lookup for a channel
+func (s *Server) handleKrakenStream() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// kraken returns a null stream when the channel is offline
+		var stream map[string]interface{}
+		if live, ok := s.streams[mux.Vars(r)["channelID"]]; ok {
+			stream = map[string]interface{}{
+				"_id":        "stream-" + live.UserID,
+				"created_at": live.StartedAt.UTC().Format(time.RFC3339),
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"stream": stream,
+		})
+	})
+}
+
+// handles pages of helix subscriptions to the token's broadcaster
+func (s *Server) handleSubscriptions() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		query := r.URL.Query()
+		broadcasterID := query.Get("broadcaster_id")
+
+		// only the broadcaster can read their subscriptions
+		token, ok := s.tokens[requestToken(r)]
+		if !ok || token.UserID != broadcasterID {
+			writeError(w, http.StatusUnauthorized, "broadcaster_id must match the token user")
+			return
+		}
+
+		// page size
+		first := helixSubscriptionsDefault
+		if len(query.Get("first")) > 0 {
+			n, err := strconv.Atoi(query.Get("first"))
+			if err != nil || n < 1 || n > helixSubscriptionsMax {
+				writeError(w, http.StatusBadRequest, "Invalid first parameter")
+				return
+			}
+			first = n
+		}
+
+		// page offset from the cursor
+		offset := 0
+		if cursor := query.Get("after"); len(cursor) > 0 {
+			n, err := decodeCursor(cursor)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			offset = n
+		}
+
+		// subscriptions in a stable order
+		subs := make([]*Subscription, 0)
+		for _, sub := range s.subscriptions[broadcasterID] {
+			subs = append(subs, sub)
+		}
+		sort.Slice(subs, func(i, j int) bool {
+			return subs[i].UserID < subs[j].UserID
+		})
+
+		resp := &subscriptionsResp{
+			Total: len(subs),
+			Data:  make([]*subscriptionResp, 0),
+		}
+
+		// build the page
+		for i := offset; i < len(subs) && i < offset+first; i++ {
+			sub := subs[i]
+
+			data := &subscriptionResp{
+				BroadcasterID: sub.BroadcasterID,
+				GifterID:      sub.GifterID,
+				IsGift:        sub.IsGift,
+				Tier:          sub.Tier,
+				PlanName:      "Tier " + sub.Tier[:1],
+				UserID:        sub.UserID,
+			}
+			if user, ok := s.users[sub.UserID]; ok {
+				data.UserLogin = user.Login
+				data.UserName = user.DisplayName
+			}
+			if user, ok := s.users[sub.GifterID]; ok {
+				data.GifterLogin = user.Login
+				data.GifterName = user.DisplayName
+			}
+
+			resp.Data = append(resp.Data, data)
+		}
+
+		// only hand out a cursor when there's another page
+		if offset+first < len(subs) {
+			resp.Pagination.Cursor = encodeCursor(offset + first)
+		}
+
+		writeHelix(w, resp)
+	})
+}
+
+// handles the all time helix bits leaderboard of the token's broadcaster
+func (s *Server) handleBitsLeaderboard() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		query := r.URL.Query()
+
+		// the leaderboard belongs to the token user
+		token, ok := s.tokens[requestToken(r)]
+		if !ok || len(token.UserID) == 0 {
+			writeError(w, http.StatusUnauthorized, "Missing user token")
+			return
+		}
+
+		// leaderboard size
+		count := helixLeaderboardDefault
+		if len(query.Get("count")) > 0 {
+			n, err := strconv.Atoi(query.Get("count"))
+			if err != nil || n < 1 || n > helixLeaderboardMax {
+				writeError(w, http.StatusBadRequest, "Invalid count parameter")
+				return
+			}
+			count = n
+		}
+
+		// highest scores first
+		entries := make([]*leaderboardResp, 0)
+		for userID, score := range s.bits[token.UserID] {
+			entry := &leaderboardResp{
+				UserID: userID,
+				Score:  score,
+			}
+			if user, ok := s.users[userID]; ok {
+				entry.UserLogin = user.Login
+				entry.UserName = user.DisplayName
+			}
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if entries[i].Score != entries[j].Score {
+				return entries[i].Score > entries[j].Score
+			}
+			return entries[i].UserID < entries[j].UserID
+		})
+
+		if len(entries) > count {
+			entries = entries[:count]
+		}
+		for i, entry := range entries {
+			entry.Rank = i + 1
+		}
+
+		writeHelix(w, map[string]interface{}{
+			"data":  entries,
+			"total": len(entries),
+		})
+	})
+}
+
+// writes a helix response with a full rate limit bucket
+func writeHelix(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Ratelimit-Limit", strconv.Itoa(helixRateLimit))
+	w.Header().Set("Ratelimit-Remaining", strconv.Itoa(helixRateLimit-1))
+	w.Header().Set("Ratelimit-Reset", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10))
+
+	writeJSON(w, http.StatusOK, data)
+}
+
+// encodes a page offset as an opaque cursor
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodes a page offset from a cursor
+func decodeCursor(cursor string) (int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(string(b))
+}
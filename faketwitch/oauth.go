@@ -0,0 +1,162 @@
+package faketwitch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// lifetime reported for every issued token
+var tokenExpiresIn = 4 * 60 * 60
+
+// tokenResp is a response from the token endpoint.
+type tokenResp struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token,omitempty"`
+	ExpiresIn    int      `json:"expires_in"`
+	Scope        []string `json:"scope"`
+	TokenType    string   `json:"token_type"`
+}
+
+// validateResp is a response from the validate endpoint.
+type validateResp struct {
+	ClientID  string   `json:"client_id"`
+	Login     string   `json:"login,omitempty"`
+	UserID    string   `json:"user_id,omitempty"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expires_in"`
+}
+
+// handles token requests for the refresh token, client
+// credentials and authorization code grants
+func (s *Server) handleToken() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var token *Token
+
+		switch r.FormValue("grant_type") {
+		case "refresh_token":
+			// rotate the refresh token along with the access token
+			current, ok := s.refresh[r.FormValue("refresh_token")]
+			if !ok {
+				writeError(w, http.StatusBadRequest, "Invalid refresh token")
+				return
+			}
+			delete(s.refresh, current.RefreshToken)
+			delete(s.tokens, current.AccessToken)
+
+			token = &Token{
+				AccessToken:  newToken(),
+				RefreshToken: newToken(),
+				UserID:       current.UserID,
+				Scopes:       current.Scopes,
+			}
+		case "client_credentials":
+			token = &Token{
+				AccessToken: newToken(),
+				Scopes:      []string{},
+			}
+		case "authorization_code":
+			// codes are single use
+			code, ok := s.codes[r.FormValue("code")]
+			if !ok {
+				writeError(w, http.StatusBadRequest, "Invalid authorization code")
+				return
+			}
+			delete(s.codes, r.FormValue("code"))
+
+			token = &Token{
+				AccessToken:  newToken(),
+				RefreshToken: newToken(),
+				UserID:       code.UserID,
+				Scopes:       code.Scopes,
+			}
+		default:
+			writeError(w, http.StatusBadRequest, "Invalid grant type")
+			return
+		}
+
+		// accept the new token
+		s.tokens[token.AccessToken] = token
+		if len(token.RefreshToken) > 0 {
+			s.refresh[token.RefreshToken] = token
+		}
+
+		writeJSON(w, http.StatusOK, &tokenResp{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			ExpiresIn:    tokenExpiresIn,
+			Scope:        token.Scopes,
+			TokenType:    "bearer",
+		})
+	})
+}
+
+// handles token validation
+func (s *Server) handleValidate() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		token, ok := s.tokens[requestToken(r)]
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "invalid access token")
+			return
+		}
+
+		resp := &validateResp{
+			ClientID:  s.ClientID,
+			UserID:    token.UserID,
+			Scopes:    token.Scopes,
+			ExpiresIn: tokenExpiresIn,
+		}
+		if user, ok := s.users[token.UserID]; ok {
+			resp.Login = user.Login
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// only lets requests with an accepted token through
+func (s *Server) authorized(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.validToken(requestToken(r)) {
+			writeError(w, http.StatusUnauthorized, "Invalid OAuth token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checks if the fake accepts an access token
+func (s *Server) validToken(accessToken string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.tokens[accessToken]
+	return ok
+}
+
+// returns the access token from the authorization header,
+// without its bearer / oauth prefix
+func requestToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if i := strings.Index(auth, " "); i >= 0 {
+		auth = auth[i+1:]
+	}
+
+	return auth
+}
+
+// creates a new random token
+func newToken() string {
+	b := make([]byte, 15)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
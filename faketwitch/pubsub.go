@@ -0,0 +1,309 @@
+package faketwitch
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	pubsubWriteWait = 1 * time.Second
+	pubsubSendSize  = 256
+
+	pubsubUpgrader = websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool { return true },
+	}
+)
+
+// pubsubRequest is a request sent by a pub sub client.
+type pubsubRequest struct {
+	Type  string `json:"type"`
+	Nonce string `json:"nonce,omitempty"`
+	Data  *struct {
+		Topics    []string `json:"topics"`
+		AuthToken string   `json:"auth_token"`
+	} `json:"data"`
+}
+
+// pubsubMessage is a message sent to a pub sub client.
+type pubsubMessage struct {
+	Type  string             `json:"type"`
+	Nonce string             `json:"nonce,omitempty"`
+	Error string             `json:"error,omitempty"`
+	Data  *pubsubMessageData `json:"data,omitempty"`
+}
+
+// pubsubMessageData is the data on a pub sub message.
+type pubsubMessageData struct {
+	Topic   string `json:"topic"`
+	Message string `json:"message"`
+}
+
+// pubsub is the scripted state of the fake pub sub websocket.
+type pubsub struct {
+	mu         sync.Mutex
+	conns      map[*pubsubConn]bool
+	listenErrs map[string]string
+	dropPongs  bool
+}
+
+// pubsubConn is a client connected to the fake pub sub websocket.
+type pubsubConn struct {
+	ws     *websocket.Conn
+	send   chan []byte
+	topics map[string]bool
+}
+
+// returns a new pub sub with no connections
+func newPubSub() *pubsub {
+	return &pubsub{
+		conns:      make(map[*pubsubConn]bool),
+		listenErrs: make(map[string]string),
+	}
+}
+
+// Publish sends a MESSAGE on a topic to every connection listening
+// to it, returning how many connections it was sent to.
+func (s *Server) Publish(topic string, message string) int {
+	p := s.pubsub
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sent := 0
+	for conn := range p.conns {
+		if !conn.topics[topic] {
+			continue
+		}
+
+		conn.write(&pubsubMessage{
+			Type: "MESSAGE",
+			Data: &pubsubMessageData{
+				Topic:   topic,
+				Message: message,
+			},
+		})
+		sent++
+	}
+
+	return sent
+}
+
+// Reconnect sends a RECONNECT to every connection.
+func (s *Server) Reconnect() {
+	p := s.pubsub
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for conn := range p.conns {
+		conn.write(&pubsubMessage{Type: "RECONNECT"})
+	}
+}
+
+// Disconnect drops every connection without warning.
+func (s *Server) Disconnect() {
+	p := s.pubsub
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for conn := range p.conns {
+		conn.ws.Close()
+	}
+}
+
+// SetListenError makes a LISTEN for the topic fail with the given
+// error, e.g. ERR_BADAUTH. An empty error clears it.
+func (s *Server) SetListenError(topic string, err string) {
+	p := s.pubsub
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(err) == 0 {
+		delete(p.listenErrs, topic)
+		return
+	}
+	p.listenErrs[topic] = err
+}
+
+// DropPongs stops the fake answering PINGs, so clients time out.
+func (s *Server) DropPongs(drop bool) {
+	p := s.pubsub
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.dropPongs = drop
+}
+
+// Listening returns the topics currently listened to across
+// every connection.
+func (s *Server) Listening() []string {
+	p := s.pubsub
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	topics := make([]string, 0)
+	for conn := range p.conns {
+		for topic := range conn.topics {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics
+}
+
+// handles pub sub websocket connections
+func (s *Server) handlePubSub() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := pubsubUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("[ERROR] faketwitch: pubsub: upgrade: %s", err)
+			return
+		}
+
+		conn := &pubsubConn{
+			ws:     ws,
+			send:   make(chan []byte, pubsubSendSize),
+			topics: make(map[string]bool),
+		}
+
+		p := s.pubsub
+		p.mu.Lock()
+		p.conns[conn] = true
+		p.mu.Unlock()
+
+		go conn.writePump()
+
+		// read until the client goes away
+		s.readPump(conn)
+
+		p.mu.Lock()
+		delete(p.conns, conn)
+		close(conn.send)
+		p.mu.Unlock()
+	})
+}
+
+// reads requests from a pub sub client
+func (s *Server) readPump(conn *pubsubConn) {
+	defer conn.ws.Close()
+
+	for {
+		_, message, err := conn.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req pubsubRequest
+		if err := json.Unmarshal(message, &req); err != nil {
+			s.pubsub.reply(conn, &pubsubMessage{Type: "RESPONSE", Error: "ERR_BADMESSAGE"})
+			continue
+		}
+
+		switch req.Type {
+		case "PING":
+			s.pubsub.pong(conn)
+		case "LISTEN", "UNLISTEN":
+			s.handleListen(conn, &req)
+		default:
+			s.pubsub.reply(conn, &pubsubMessage{Type: "RESPONSE", Nonce: req.Nonce, Error: "ERR_BADMESSAGE"})
+		}
+	}
+}
+
+// handles a LISTEN or UNLISTEN request, answering with any scripted
+// error or ERR_BADAUTH when the auth token isn't accepted
+func (s *Server) handleListen(conn *pubsubConn, req *pubsubRequest) {
+	if req.Data == nil || len(req.Data.Topics) == 0 {
+		s.pubsub.reply(conn, &pubsubMessage{Type: "RESPONSE", Nonce: req.Nonce, Error: "ERR_BADMESSAGE"})
+		return
+	}
+
+	validToken := s.validToken(req.Data.AuthToken)
+
+	p := s.pubsub
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// scripted errors win over everything else
+	resp := &pubsubMessage{Type: "RESPONSE", Nonce: req.Nonce}
+	for _, topic := range req.Data.Topics {
+		if err, ok := p.listenErrs[topic]; ok {
+			resp.Error = err
+			break
+		}
+	}
+	if len(resp.Error) == 0 && req.Type == "LISTEN" && !validToken {
+		resp.Error = "ERR_BADAUTH"
+	}
+
+	// update the topics on the connection
+	if len(resp.Error) == 0 {
+		for _, topic := range req.Data.Topics {
+			if req.Type == "LISTEN" {
+				conn.topics[topic] = true
+			} else {
+				delete(conn.topics, topic)
+			}
+		}
+	}
+
+	conn.write(resp)
+}
+
+// answers a PING unless pongs are being dropped
+func (p *pubsub) pong(conn *pubsubConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.dropPongs {
+		return
+	}
+
+	conn.write(&pubsubMessage{Type: "PONG"})
+}
+
+// sends a message to a single connection
+func (p *pubsub) reply(conn *pubsubConn, msg *pubsubMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn.write(msg)
+}
+
+// queues a message on the connection, called with the pub sub lock
+// held so it never races the send channel closing
+func (conn *pubsubConn) write(msg *pubsubMessage) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[ERROR] faketwitch: pubsub: marshal: %s", err)
+		return
+	}
+
+	select {
+	case conn.send <- data:
+	default:
+		// client isn't reading, so drop it
+		conn.ws.Close()
+	}
+}
+
+// writes queued messages to the client
+func (conn *pubsubConn) writePump() {
+	defer conn.ws.Close()
+
+	for data := range conn.send {
+		conn.ws.SetWriteDeadline(time.Now().Add(pubsubWriteWait))
+		if err := conn.ws.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+	}
+}
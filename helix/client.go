@@ -0,0 +1,210 @@
+package helix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/backoff"
+)
+
+var (
+	maxRetries = 5
+
+	backoffMin    = 1 * time.Second
+	backoffMax    = 1 * time.Minute
+	backoffFactor = float64(2)
+	backoffJitter = true
+)
+
+// Client is a twitch api client that throttles to the rate limit
+// twitch reports and retries rate limited and server errors.
+type Client struct {
+	clientID string
+	tokens   TokenSource
+
+	client *http.Client
+
+	mu        sync.Mutex
+	remaining int
+	reset     time.Time
+}
+
+// NewClient returns a new client sending the authorization header
+// from the token source with each request.
+func NewClient(clientID string, tokens TokenSource) *Client {
+	return &Client{
+		clientID: clientID,
+		tokens:   tokens,
+
+		client: &http.Client{},
+
+		remaining: -1,
+	}
+}
+
+// Get does a get request and returns the response body.
+func (c *Client) Get(ctx context.Context, url string, header http.Header) ([]byte, error) {
+	_, data, err := c.Do(ctx, "GET", url, header, nil)
+	return data, err
+}
+
+// Do does a request and returns the response status code and body.
+// Rate limited and server error responses are retried with backoff,
+// unauthorized responses are retried once with a new token.
+func (c *Client) Do(ctx context.Context, method string, url string, header http.Header, body []byte) (int, []byte, error) {
+	b := &backoff.Backoff{
+		Min:    backoffMin,
+		Max:    backoffMax,
+		Factor: backoffFactor,
+		Jitter: backoffJitter,
+	}
+
+	renewed := false
+
+	for attempt := 0; ; attempt++ {
+		// wait for the rate limit to reset if we've used it up
+		if err := c.throttle(ctx); err != nil {
+			return 0, nil, err
+		}
+
+		// get the current token
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return 0, nil, fmt.Errorf("token: %s", err)
+		}
+
+		status, data, err := c.do(ctx, method, url, token, header, body)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch {
+		case status >= 200 && status < 300:
+			return status, data, nil
+		case status == http.StatusUnauthorized && !renewed:
+			c.tokens.Invalidate(token)
+			renewed = true
+			continue
+		case status == http.StatusUnauthorized:
+			return status, data, ErrUnauthorized
+		case status == http.StatusNotFound:
+			return status, data, ErrNotFound
+		case status != http.StatusTooManyRequests && status < 500:
+			return status, data, &StatusError{StatusCode: status, Body: data}
+		}
+
+		// out of retries
+		if attempt >= maxRetries {
+			return status, data, &StatusError{StatusCode: status, Body: data}
+		}
+
+		// rate limited requests wait on the throttle, everything
+		// else backs off
+		if status == http.StatusTooManyRequests && c.limited() {
+			continue
+		}
+
+		if err := sleep(ctx, b.Duration()); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// does a single request, recording the rate limit headers
+func (c *Client) do(ctx context.Context, method string, url string, token string, header http.Header, body []byte) (int, []byte, error) {
+	// create new request
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("error generating request: %v", err)
+	}
+	req = req.WithContext(ctx)
+
+	// add oauth token and client id to headers
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Client-ID", c.clientID)
+
+	// add request headers, overriding the defaults
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	// do request
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error doing request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// track rate limit
+	c.updateLimit(resp.Header)
+
+	// read body
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error reading body: %v", err)
+	}
+
+	return resp.StatusCode, data, nil
+}
+
+// records the rate limit from response headers
+func (c *Client) updateLimit(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(header.Get("Ratelimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remaining = remaining
+	c.reset = time.Unix(reset, 0)
+}
+
+// returns if the rate limit is used up until a known reset
+func (c *Client) limited() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.remaining == 0 && time.Now().Before(c.reset)
+}
+
+// waits for the rate limit to reset if there are no requests left
+func (c *Client) throttle(ctx context.Context) error {
+	c.mu.Lock()
+	wait := time.Duration(0)
+	if c.remaining == 0 {
+		wait = time.Until(c.reset)
+	}
+	c.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+
+	return sleep(ctx, wait)
+}
+
+// sleeps for the duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package helix
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnauthorized is returned when twitch rejects the token.
+	ErrUnauthorized = errors.New("helix: unauthorized")
+	// ErrNotFound is returned when twitch can't find the resource.
+	ErrNotFound = errors.New("helix: not found")
+)
+
+// StatusError is returned for any other unsuccessful response.
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("helix: invalid response code: %d: %s", e.StatusCode, string(e.Body))
+}
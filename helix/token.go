@@ -0,0 +1,25 @@
+package helix
+
+import (
+	"context"
+)
+
+// TokenSource provides the authorization header sent with requests.
+type TokenSource interface {
+	// Token returns the authorization header value.
+	Token(ctx context.Context) (string, error)
+	// Invalidate is called when twitch rejects a token so the
+	// next call to Token returns a new one.
+	Invalidate(token string)
+}
+
+// StaticToken is a token source that always returns the same header.
+type StaticToken string
+
+// Token returns the static authorization header.
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// Invalidate does nothing since a static token can't be renewed.
+func (t StaticToken) Invalidate(token string) {}
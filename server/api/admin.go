@@ -0,0 +1,42 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// only lets requests carrying the admin token through
+func (api *API) admin(next http.Handler) http.Handler {
+	return api.adminToken(next, func(r *http.Request) string {
+		return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	})
+}
+
+// only lets requests carrying the admin token in the query through, for
+// routes opened in a browser, which can't send the header
+func (api *API) adminQuery(next http.Handler) http.Handler {
+	return api.adminToken(next, func(r *http.Request) string {
+		return r.URL.Query().Get("admin_token")
+	})
+}
+
+// only lets requests whose token matches the admin token through
+func (api *API) adminToken(next http.Handler, token func(r *http.Request) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// admin routes are off without a token
+		if len(api.config.APIAdminToken) == 0 {
+			api.handleError(w, 403, fmt.Errorf("admin api not configured"))
+			return
+		}
+
+		// check token
+		if subtle.ConstantTimeCompare([]byte(token(r)), []byte(api.config.APIAdminToken)) != 1 {
+			api.handleError(w, 401, fmt.Errorf("invalid admin token"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
+)
+
+// DeadLettersResp is a page of dead letters.
+type DeadLettersResp struct {
+	DeadLetters []*database.DeadLetter `json:"dead_letters"`
+	// dead letters held in memory until the database is back
+	Unsaved int `json:"unsaved"`
+}
+
+// handleAdminDeadLetters
+func (api *API) handleAdminDeadLetters() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleAdminDeadLettersGet(w, r)
+		case "DELETE":
+			api.handleAdminDeadLettersDelete(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleAdminDeadLettersGet
+func (api *API) handleAdminDeadLettersGet(w http.ResponseWriter, r *http.Request) {
+	var (
+		limitDefault  = 20
+		limitMax      = 100
+		offsetDefault = 0
+	)
+
+	// get query vars
+	v := r.URL.Query()
+
+	// get vars
+	limit, _ := strconv.Atoi(v.Get("limit"))
+	offset, _ := strconv.Atoi(v.Get("offset"))
+
+	// make sure we have at least default value for limit
+	if limit <= 0 {
+		limit = limitDefault
+	}
+
+	// check limit
+	if limit > limitMax {
+		limit = limitMax
+	}
+
+	// check offset
+	if offset <= offsetDefault {
+		offset = offsetDefault
+	}
+
+	// get dead letters
+	deadLetters, err := api.database.GetDeadLetters(limit, offset)
+	if err != nil {
+		log.Printf("[ERROR] get dead letters: %s", err)
+		api.handleError(w, 500, fmt.Errorf("unable to get dead letters"))
+		return
+	}
+
+	api.handleSuccess(w, &DeadLettersResp{
+		DeadLetters: deadLetters,
+		Unsaved:     api.twitch.UnsavedDeadLetters(),
+	})
+}
+
+// handleAdminDeadLettersDelete
+func (api *API) handleAdminDeadLettersDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+
+	// discard the dead letter
+	if err := api.database.RemoveDeadLetter(id); err != nil {
+		api.handleDeadLetterError(w, err)
+		return
+	}
+
+	api.handleSuccess(w, id)
+}
+
+// handleAdminDeadLettersRetry
+func (api *API) handleAdminDeadLettersRetry() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			api.handleAdminDeadLettersRetryPost(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleAdminDeadLettersRetryPost
+func (api *API) handleAdminDeadLettersRetryPost(w http.ResponseWriter, r *http.Request) {
+	var (
+		retry *twitch.DeadLetterRetry
+		err   error
+	)
+
+	// retry one dead letter, or all of them without an id
+	if id := r.URL.Query().Get("id"); len(id) > 0 {
+		retry, err = api.twitch.RetryDeadLetter(id)
+	} else {
+		retry, err = api.twitch.RetryDeadLetters()
+	}
+	if err != nil {
+		api.handleDeadLetterError(w, err)
+		return
+	}
+
+	api.handleSuccess(w, retry)
+}
+
+// handle a dead letter error response
+func (api *API) handleDeadLetterError(w http.ResponseWriter, err error) {
+	switch err {
+	case database.ErrDeadLetterNotFound:
+		api.handleError(w, 404, err)
+	default:
+		log.Printf("[ERROR] admin dead letters: %s", err)
+		api.handleError(w, 500, fmt.Errorf("unable to update dead letters"))
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
+)
+
+// TopicReq is a request to add a pub sub topic to a channel.
+type TopicReq struct {
+	ChannelID string `json:"channelID"`
+	Topic     string `json:"topic"`
+}
+
+// handleAdminTopics
+func (api *API) handleAdminTopics() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleAdminTopicsGet(w, r)
+		case "POST":
+			api.handleAdminTopicsPost(w, r)
+		case "DELETE":
+			api.handleAdminTopicsDelete(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleAdminTopicsGet
+func (api *API) handleAdminTopicsGet(w http.ResponseWriter, r *http.Request) {
+	// get topics, for one channel if asked
+	api.handleTopics(w, r.URL.Query().Get("channelID"))
+}
+
+// handleAdminTopicsPost
+func (api *API) handleAdminTopicsPost(w http.ResponseWriter, r *http.Request) {
+	// decode request
+	var req TopicReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.handleError(w, 400, fmt.Errorf("invalid request body"))
+		return
+	}
+
+	// listen for the topic
+	if err := api.twitch.AddPubSubTopic(req.ChannelID, req.Topic); err != nil {
+		api.handleTopicError(w, err)
+		return
+	}
+
+	api.handleTopics(w, req.ChannelID)
+}
+
+// handleAdminTopicsDelete
+func (api *API) handleAdminTopicsDelete(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+
+	// stop listening for the topic
+	if err := api.twitch.RemovePubSubTopic(v.Get("channelID"), v.Get("topic")); err != nil {
+		api.handleTopicError(w, err)
+		return
+	}
+
+	api.handleTopics(w, v.Get("channelID"))
+}
+
+// respond with the topics of the channels
+func (api *API) handleTopics(w http.ResponseWriter, channelID string) {
+	topics, err := api.twitch.PubSubTopics(channelID)
+	if err != nil {
+		api.handleTopicError(w, err)
+		return
+	}
+
+	api.handleSuccess(w, topics)
+}
+
+// handle a pub sub topic error response
+func (api *API) handleTopicError(w http.ResponseWriter, err error) {
+	switch err {
+	case twitch.ErrUnknownChannel:
+		api.handleError(w, 404, err)
+	case twitch.ErrUnknownTopic:
+		api.handleError(w, 422, err)
+	case twitch.ErrConnFull:
+		api.handleError(w, 409, err)
+	default:
+		log.Printf("[ERROR] admin topics: %s", err)
+		api.handleError(w, 500, fmt.Errorf("unable to update topics"))
+	}
+}
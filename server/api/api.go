@@ -1,11 +1,13 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/handlers"
@@ -13,33 +15,58 @@ import (
 
 	config "github.com/codephobia/twitch-eos-thanks/server/config"
 	database "github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
 )
 
 // API is the web api.
 type API struct {
 	config   *config.Config
 	database *database.Database
+	twitch   *twitch.Twitch
+	events   *events.Bus
 
 	server *http.Server
+
+	// apps streaming events
+	live *liveEvents
+
+	authMu     sync.Mutex
+	authStates map[string]*authState
+
+	eventsubMu   sync.Mutex
+	eventsubSeen map[string]time.Time
 }
 
-// NewAPI returns a new api.
-func NewAPI(c *config.Config, db *database.Database) *API {
-	return &API{
+// NewAPI returns a new api, streaming the events on the bus to apps.
+func NewAPI(c *config.Config, db *database.Database, t *twitch.Twitch, bus *events.Bus) *API {
+	live := newLiveEvents()
+	bus.Subscribe("live", live, liveEventsBuffer)
+
+	api := &API{
 		config:   c,
 		database: db,
+		twitch:   t,
+		events:   bus,
+
+		live: live,
+
+		authStates:   make(map[string]*authState),
+		eventsubSeen: make(map[string]time.Time),
 	}
-}
 
-// Init initializes the api.
-func (api *API) Init() error {
 	// create the server
 	api.server = &http.Server{
-		Handler:      handlers.CompressHandler(handlers.CORS()(api.Handler())),
+		Handler:      api.streaming(handlers.CompressHandler(handlers.CORS()(api.Handler()))),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
+	return api
+}
+
+// Init initializes the api.
+func (api *API) Init() error {
 	// create a listener
 	hostURL := strings.Join([]string{api.config.APIHost, ":", api.config.APIPort}, "")
 	listener, err := net.Listen("tcp", hostURL)
@@ -54,23 +81,68 @@ func (api *API) Init() error {
 	return nil
 }
 
+// Shutdown stops the api server, waiting for requests in flight until
+// the context is done.
+func (api *API) Shutdown(ctx context.Context) error {
+	return api.server.Shutdown(ctx)
+}
+
 // Handler handles incoming api routes.
 func (api *API) Handler() http.Handler {
 	// create router
 	r := mux.NewRouter()
 
+	// twitch oauth, only the admin can start a login, the callback
+	// is checked against the state handed out by the login
+	r.Handle("/auth/login", api.adminQuery(api.handleAuthLogin()))
+	r.Handle("/auth/callback", api.handleAuthCallback())
+
 	// follow webhook
 	r.Handle("/follow", api.handleFollow())
 
+	// eventsub webhook
+	r.Handle("/eventsub", api.handleEventSub())
+
+	// get connection status
+	r.Handle("/status", api.handleStatus())
+
+	// stream events as they happen
+	r.Handle("/events", api.handleEvents())
+
+	// get server metrics
+	r.Handle("/metrics", api.handleMetrics())
+
+	// manage pub sub topics
+	r.Handle("/admin/pubsub/topics", api.admin(api.handleAdminTopics()))
+
+	// manage dead letters
+	r.Handle("/admin/dead-letters", api.admin(api.handleAdminDeadLetters()))
+	r.Handle("/admin/dead-letters/retry", api.admin(api.handleAdminDeadLettersRetry()))
+
 	// get followers
 	r.Handle("/followers", api.handleFollowers())
 
+	// get unfollow report
+	r.Handle("/unfollowers", api.handleUnfollowers())
+
 	// get subscribers
 	r.Handle("/subscribers", api.handleSubscribers())
 
+	// get gifts
+	r.Handle("/gifts", api.handleGifts())
+
 	// get bits
 	r.Handle("/bits", api.handleBits())
 
+	// get commerce
+	r.Handle("/commerce", api.handleCommerce())
+
+	// get redemptions
+	r.Handle("/redemptions", api.handleRedemptions())
+
+	// get raids
+	r.Handle("/raids", api.handleRaids())
+
 	// return router
 	return r
 }
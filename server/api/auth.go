@@ -0,0 +1,250 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	config "github.com/codephobia/twitch-eos-thanks/server/config"
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
+)
+
+var (
+	authStateCookie = "twitch_oauth_state"
+	authStateTTL    = 10 * time.Minute
+)
+
+// AuthResp is a successful oauth callback response.
+type AuthResp struct {
+	ChannelID string   `json:"channelID"`
+	Login     string   `json:"login"`
+	Scopes    []string `json:"scopes"`
+}
+
+// handleAuthLogin
+func (api *API) handleAuthLogin() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleAuthLoginGet(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleAuthLoginGet
+func (api *API) handleAuthLoginGet(w http.ResponseWriter, r *http.Request) {
+	// make sure the app can redirect back to us
+	if len(api.config.TwitchRedirectURI) == 0 {
+		api.handleError(w, 500, fmt.Errorf("twitch redirect uri not configured"))
+		return
+	}
+
+	// the login is for a single channel
+	channelID := r.URL.Query().Get("channelID")
+	matched, err := regexp.MatchString("^[0-9]+$", channelID)
+	if err != nil || !matched {
+		api.handleError(w, 422, fmt.Errorf("invalid channel id"))
+		return
+	}
+
+	// create state to tie the callback to this browser and channel
+	state, err := api.newAuthState(channelID)
+	if err != nil {
+		log.Printf("[ERROR] auth login: %s", err)
+		api.handleError(w, 500, fmt.Errorf("unable to create state"))
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     authStateCookie,
+		Value:    state,
+		Path:     "/auth",
+		MaxAge:   int(authStateTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// send user to twitch
+	http.Redirect(w, r, api.twitch.Tokens().AuthorizeURL(state, api.authScopes(channelID)), http.StatusFound)
+}
+
+// handleAuthCallback
+func (api *API) handleAuthCallback() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleAuthCallbackGet(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleAuthCallbackGet
+func (api *API) handleAuthCallbackGet(w http.ResponseWriter, r *http.Request) {
+	// get query vars
+	v := r.URL.Query()
+
+	// check state against the cookie and the states we handed out
+	cookie, err := r.Cookie(authStateCookie)
+	if err != nil {
+		api.handleError(w, 403, fmt.Errorf("invalid state"))
+		return
+	}
+	channelID, ok := api.useAuthState(v.Get("state"), cookie.Value)
+	if !ok {
+		api.handleError(w, 403, fmt.Errorf("invalid state"))
+		return
+	}
+
+	// clear state cookie
+	http.SetCookie(w, &http.Cookie{
+		Name:   authStateCookie,
+		Path:   "/auth",
+		MaxAge: -1,
+	})
+
+	// user denied the app
+	if authErr := v.Get("error"); len(authErr) > 0 {
+		api.handleError(w, 400, fmt.Errorf("authorization failed: %s: %s", authErr, v.Get("error_description")))
+		return
+	}
+
+	code := v.Get("code")
+	if len(code) == 0 {
+		api.handleError(w, 422, fmt.Errorf("missing code"))
+		return
+	}
+
+	tokens := api.twitch.Tokens()
+
+	// exchange code for tokens
+	tokenResp, err := tokens.ExchangeCode(code)
+	if err != nil {
+		log.Printf("[ERROR] auth callback: %s", err)
+		api.handleError(w, 502, fmt.Errorf("unable to exchange code"))
+		return
+	}
+
+	// find out which channel the tokens belong to
+	validateResp, err := tokens.ValidateToken(tokenResp.AccessToken)
+	if err != nil {
+		log.Printf("[ERROR] auth callback: %s", err)
+		api.handleError(w, 502, fmt.Errorf("unable to validate token"))
+		return
+	}
+
+	// only the channel the login was started for can be authorized
+	if validateResp.UserID != channelID {
+		api.handleError(w, 403, fmt.Errorf("logged in as %s, not channel %s", validateResp.Login, channelID))
+		return
+	}
+
+	// make sure the scopes cover the channel topics
+	required := api.authScopes(channelID)
+	if missing := twitch.MissingScopes(tokenResp.Scope, required); len(missing) > 0 {
+		api.handleError(w, 403, fmt.Errorf("missing scopes: %s", strings.Join(missing, " ")))
+		return
+	}
+
+	// store tokens
+	if err := tokens.SetToken(validateResp.UserID, tokenResp.AccessToken, tokenResp.RefreshToken); err != nil {
+		log.Printf("[ERROR] auth callback: %s", err)
+		api.handleError(w, 500, fmt.Errorf("unable to store tokens"))
+		return
+	}
+
+	api.handleSuccess(w, &AuthResp{
+		ChannelID: validateResp.UserID,
+		Login:     validateResp.Login,
+		Scopes:    tokenResp.Scope,
+	})
+}
+
+// returns the scopes a channel needs for its transport and topics
+func (api *API) authScopes(channelID string) []string {
+	var topics []string
+	if channel, ok := api.config.Channel(channelID); ok {
+		topics = api.config.ChannelTopics(channel)
+	}
+
+	return twitch.Scopes(api.authTransport(channelID), topics)
+}
+
+// returns the transport a channel uses, or the default transport
+// for channels that aren't configured yet
+func (api *API) authTransport(channelID string) string {
+	if channel, ok := api.config.Channel(channelID); ok {
+		return channel.Transport
+	}
+
+	if len(api.config.TwitchTransport) > 0 {
+		return api.config.TwitchTransport
+	}
+
+	return config.TransportPubSub
+}
+
+// authState is an oauth state handed out for a channel login.
+type authState struct {
+	channelID string
+	expires   time.Time
+}
+
+// creates a new oauth state for a channel login
+func (api *API) newAuthState(channelID string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(b)
+
+	api.authMu.Lock()
+	defer api.authMu.Unlock()
+
+	// drop expired states
+	now := time.Now()
+	for s, authState := range api.authStates {
+		if now.After(authState.expires) {
+			delete(api.authStates, s)
+		}
+	}
+
+	api.authStates[state] = &authState{
+		channelID: channelID,
+		expires:   now.Add(authStateTTL),
+	}
+
+	return state, nil
+}
+
+// checks an oauth state matches the cookie and hasn't expired, returning
+// the channel it was handed out for, a state can only be used once
+func (api *API) useAuthState(state string, cookieState string) (string, bool) {
+	if len(state) == 0 || subtle.ConstantTimeCompare([]byte(state), []byte(cookieState)) != 1 {
+		return "", false
+	}
+
+	api.authMu.Lock()
+	defer api.authMu.Unlock()
+
+	authState, ok := api.authStates[state]
+	if !ok {
+		return "", false
+	}
+	delete(api.authStates, state)
+
+	if time.Now().After(authState.expires) {
+		return "", false
+	}
+
+	return authState.channelID, true
+}
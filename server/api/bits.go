@@ -66,5 +66,8 @@ func (api *API) handleBitsGet(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[ERROR] get bits: %s", err)
 	}
 
+	// add user profiles
+	api.embedBitUsers(bits)
+
 	api.handleSuccess(w, bits)
 }
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+)
+
+var (
+	liveEventsBuffer    = 256
+	liveClientBuffer    = 64
+	liveKeepAlivePeriod = 30 * time.Second
+)
+
+// liveEvents hands the events on the bus to every app streaming them,
+// dropping events for an app too slow to keep up.
+type liveEvents struct {
+	mu sync.Mutex
+	// channel id each app streams, every channel when empty
+	clients map[chan events.Event]string
+}
+
+// returns new live events with no apps streaming
+func newLiveEvents() *liveEvents {
+	return &liveEvents{
+		clients: make(map[chan events.Event]string),
+	}
+}
+
+// HandleEvent hands an event to the apps streaming its channel.
+func (l *liveEvents) HandleEvent(e events.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dropped := 0
+	for client, channelID := range l.clients {
+		if len(channelID) > 0 && channelID != e.Metadata().ChannelID {
+			continue
+		}
+
+		select {
+		case client <- e:
+		default:
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		return fmt.Errorf("dropped for %d apps falling behind", dropped)
+	}
+
+	return nil
+}
+
+// adds an app streaming the events of a channel
+func (l *liveEvents) add(channelID string) chan events.Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	client := make(chan events.Event, liveClientBuffer)
+	l.clients[client] = channelID
+
+	return client
+}
+
+// removes an app that stopped streaming
+func (l *liveEvents) remove(client chan events.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.clients, client)
+}
+
+// handleEvents
+func (api *API) handleEvents() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleEventsGet(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleEventsGet streams events as server sent events until the app
+// goes away, optionally only for the channel_id given.
+func (api *API) handleEventsGet(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		api.handleError(w, 500, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	client := api.live.add(r.URL.Query().Get("channel_id"))
+	defer api.live.remove(client)
+
+	// add headers to response
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// keep idle streams from being closed along the way
+	keepAlive := time.NewTicker(liveKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e := <-client:
+			data, err := json.Marshal(e)
+			if err != nil {
+				log.Printf("[ERROR] events: unable to encode %s event: %s", e.Kind(), err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Kind(), data); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// lifts the write timeout of the event stream, which stays open
+func (api *API) streaming(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/events" {
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+				log.Printf("[ERROR] events: %s", err)
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
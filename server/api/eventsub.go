@@ -0,0 +1,146 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
+)
+
+var (
+	eventsubMaxBodySize = int64(1 << 20)
+	// messages older than this are rejected as replays
+	eventsubMaxMessageAge = 10 * time.Minute
+)
+
+// handleEventSub
+func (api *API) handleEventSub() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			api.handleEventSubPost(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleEventSubPost
+func (api *API) handleEventSubPost(w http.ResponseWriter, r *http.Request) {
+	// close body
+	defer r.Body.Close()
+
+	// read the message
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, eventsubMaxBodySize))
+	if err != nil {
+		api.handleError(w, 400, fmt.Errorf("unable to read body"))
+		return
+	}
+
+	// get headers
+	messageID := r.Header.Get("Twitch-Eventsub-Message-Id")
+	messageTimestamp := r.Header.Get("Twitch-Eventsub-Message-Timestamp")
+	messageSignature := r.Header.Get("Twitch-Eventsub-Message-Signature")
+	messageType := twitch.EventSubType(r.Header.Get("Twitch-Eventsub-Message-Type"))
+	subscriptionType := twitch.EventSubSubscriptionType(r.Header.Get("Twitch-Eventsub-Subscription-Type"))
+
+	// reject anything not signed with the eventsub secret
+	if !validEventSubSignature(api.config.TwitchEventSubSecret, messageID, messageTimestamp, messageSignature, body) {
+		log.Printf("[ERROR] eventsub: invalid signature for message: %s", messageID)
+		api.handleError(w, 403, fmt.Errorf("invalid signature"))
+		return
+	}
+
+	// reject old messages
+	timestamp, err := time.Parse(time.RFC3339Nano, messageTimestamp)
+	if err != nil || time.Since(timestamp) > eventsubMaxMessageAge {
+		log.Printf("[ERROR] eventsub: message too old: %s: %s", messageID, messageTimestamp)
+		api.handleError(w, 403, fmt.Errorf("message too old"))
+		return
+	}
+
+	// drop messages we've already handled, twitch retries on
+	// anything but a success so answer as if it worked
+	if !api.useEventSubMessageID(messageID, timestamp) {
+		log.Printf("[INFO] eventsub: dropping replayed message: %s", messageID)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// decode the payload
+	var payload twitch.EventSubMessagePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("[ERROR] eventsub: unable to decode payload: %s", err)
+		api.handleError(w, 400, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	switch messageType {
+	case twitch.EventSubTypeVerification:
+		log.Printf("[INFO] eventsub: verifying subscription: %s", subscriptionType)
+
+		w.Header().Add("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(payload.Challenge))
+	case twitch.EventSubTypeNotification:
+		api.twitch.HandleEventSubNotification(subscriptionType, messageID, messageTimestamp, payload.Event)
+		w.WriteHeader(http.StatusNoContent)
+	case twitch.EventSubTypeRevocation:
+		api.twitch.HandleEventSubRevocation(payload.Subscription)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		log.Printf("[ERROR] eventsub: invalid message type: %s", messageType)
+		api.handleError(w, 400, fmt.Errorf("invalid message type"))
+	}
+}
+
+// records an eventsub message id, returning false if it was
+// already seen
+func (api *API) useEventSubMessageID(messageID string, timestamp time.Time) bool {
+	api.eventsubMu.Lock()
+	defer api.eventsubMu.Unlock()
+
+	// drop ids old enough to be rejected by timestamp anyway
+	now := time.Now()
+	for id, seen := range api.eventsubSeen {
+		if now.Sub(seen) > eventsubMaxMessageAge {
+			delete(api.eventsubSeen, id)
+		}
+	}
+
+	if _, ok := api.eventsubSeen[messageID]; ok {
+		return false
+	}
+	api.eventsubSeen[messageID] = timestamp
+
+	return true
+}
+
+// checks an eventsub signature header is a sha256 hmac of the
+// message id, timestamp and body
+func validEventSubSignature(secret string, messageID string, timestamp string, signature string, body []byte) bool {
+	if len(secret) == 0 || len(messageID) == 0 || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(messageID))
+	mac.Write([]byte(timestamp))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestValidEventSubSignature(t *testing.T) {
+	messageID := "message-1"
+	timestamp := "2020-01-01T00:00:00Z"
+	body := `{"subscription":{"type":"channel.follow"},"event":{"user_id":"2"}}`
+	signature := signWebhook("secret", messageID, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		messageID string
+		timestamp string
+		signature string
+		body      string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", messageID: messageID, timestamp: timestamp, signature: signature, body: body, want: true},
+		{name: "wrong secret", secret: "other", messageID: messageID, timestamp: timestamp, signature: signature, body: body, want: false},
+		{name: "other message id", secret: "secret", messageID: "message-2", timestamp: timestamp, signature: signature, body: body, want: false},
+		{name: "other timestamp", secret: "secret", messageID: messageID, timestamp: "2020-01-01T00:00:01Z", signature: signature, body: body, want: false},
+		{name: "changed body", secret: "secret", messageID: messageID, timestamp: timestamp, signature: signature, body: body + " ", want: false},
+		{name: "body only", secret: "secret", messageID: messageID, timestamp: timestamp, signature: signWebhook("secret", body), body: body, want: false},
+		{name: "no secret", secret: "", messageID: messageID, timestamp: timestamp, signature: signWebhook("", messageID, timestamp, body), body: body, want: false},
+		{name: "no message id", secret: "secret", messageID: "", timestamp: timestamp, signature: signWebhook("secret", timestamp, body), body: body, want: false},
+		{name: "bad hex", secret: "secret", messageID: messageID, timestamp: timestamp, signature: "sha256=zz", body: body, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validEventSubSignature(tt.secret, tt.messageID, tt.timestamp, tt.signature, []byte(tt.body)); got != tt.want {
+				t.Errorf("validEventSubSignature() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,13 +1,26 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	database "github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
+)
+
+var (
+	followMaxBodySize = int64(1 << 20)
 )
 
 // Follow is a twitch follow.
@@ -38,46 +51,112 @@ func (api *API) handleFollowGet(w http.ResponseWriter, r *http.Request) {
 	// get query vars
 	v := r.URL.Query()
 
-	// TODO: add secret validation
 	// get vars
 	hubMode := v.Get("hub.mode")
-	//hubTopic := v.Get("hub.topic")
-	//hubLeaseSeconds := v.Get("hub.lease_seconds")
+	hubTopic := v.Get("hub.topic")
+	hubLeaseSeconds, _ := strconv.Atoi(v.Get("hub.lease_seconds"))
 	hubChallenge := v.Get("hub.challenge")
-	//hubReason := v.Get("hub.reason")
+	hubReason := v.Get("hub.reason")
+
+	// close body
+	defer r.Body.Close()
+
+	// only answer for subscriptions we asked for
+	webhook, err := api.database.GetWebhook(hubTopic)
+	if err != nil {
+		log.Printf("[ERROR] follow: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if webhook == nil {
+		log.Printf("[ERROR] follow: unknown topic: %s", hubTopic)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 
 	switch hubMode {
 	case "subscribe":
+		// store the lease twitch granted
+		if err := api.database.VerifyWebhook(hubTopic, hubLeaseSeconds); err != nil {
+			log.Printf("[ERROR] follow: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(hubChallenge))
+	case "unsubscribe":
+		if err := api.database.RemoveWebhook(hubTopic); err != nil {
+			log.Printf("[ERROR] follow: %s", err)
+		}
+
 		w.WriteHeader(http.StatusAccepted)
 		w.Write([]byte(hubChallenge))
 	case "denied":
+		log.Printf("[ERROR] follow: subscription denied: %s: %s", hubTopic, hubReason)
+
+		if err := api.database.RemoveWebhook(hubTopic); err != nil {
+			log.Printf("[ERROR] follow: %s", err)
+		}
+
 		w.WriteHeader(http.StatusOK)
 	default:
 		w.WriteHeader(http.StatusOK)
 		log.Printf("[ERROR] invalid request mode: %s", hubMode)
 	}
-
-	// close body
-	r.Body.Close()
 }
 
 // handleFollowPost
 func (api *API) handleFollowPost(w http.ResponseWriter, r *http.Request) {
+	// close body
+	defer r.Body.Close()
+
+	// read the notification
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, followMaxBodySize))
+	if err != nil {
+		api.handleError(w, 400, fmt.Errorf("unable to read body"))
+		return
+	}
+
+	// find the subscription the notification is for
+	webhook, err := api.database.GetWebhook(webhookTopic(r.Header.Get("Link")))
+	if err != nil {
+		log.Printf("[ERROR] follow: %s", err)
+		api.handleError(w, 500, fmt.Errorf("unable to get subscription"))
+		return
+	}
+	if webhook == nil {
+		api.handleError(w, 403, fmt.Errorf("unknown subscription"))
+		return
+	}
+
+	// reject anything not signed with the subscription secret
+	if !validWebhookSignature(webhook.Secret, r.Header.Get("X-Hub-Signature"), body) {
+		log.Printf("[ERROR] follow: invalid signature for topic: %s", webhook.Topic)
+		api.handleError(w, 403, fmt.Errorf("invalid signature"))
+		return
+	}
+
 	// add headers to response
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	// decode the notification
 	var f Follow
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&f)
-	if err != nil {
+	if err := json.Unmarshal(body, &f); err != nil {
 		log.Printf("[ERROR] follow: unable to decode notification: %s", err)
 		return
 	}
 
 	// loop through all follows on payload
+	ids := make([]string, 0)
 	for _, newFollow := range f.Data {
+		// only accept follows for the subscribed channel
+		if newFollow.ToID != webhook.ChannelID {
+			log.Printf("[ERROR] follow: follow for channel [%s] on channel [%s] subscription", newFollow.ToID, webhook.ChannelID)
+			continue
+		}
+
 		// convert time from string
 		t, err := time.Parse(time.RFC3339, newFollow.Timestamp)
 		if err != nil {
@@ -85,18 +164,58 @@ func (api *API) handleFollowPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// build follower for db
-		follower := &database.Follower{
-			ChannelID:  newFollow.ToID,
-			FollowerID: newFollow.FromID,
-			Timestamp:  t,
+		// count follows twitch sent again instead of saving them
+		if err := api.events.Publish(&events.Follow{
+			Meta: events.Meta{
+				Source:    events.SourceWebhook,
+				ChannelID: newFollow.ToID,
+				Timestamp: t,
+			},
+			UserID: newFollow.FromID,
+		}); err == database.ErrDuplicate {
+			api.twitch.Metrics().Inc(twitch.MetricDuplicates + twitch.EventKindFollow)
+		} else if err != nil {
+			log.Printf("[ERROR] unable to add follower: %s", err)
 		}
 
-		if err := api.database.AddFollower(follower); err != nil {
-			log.Printf("[ERROR] unable to add follower: %s", err)
+		ids = append(ids, newFollow.FromID)
+	}
+
+	// cache the follower profiles
+	api.twitch.ResolveUsersAsync(ids...)
+}
+
+// returns the topic from the link header of a webhook notification
+func webhookTopic(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		params := strings.Split(part, ";")
+		if len(params) < 2 {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			if strings.TrimSpace(param) == `rel="self"` {
+				return strings.Trim(strings.TrimSpace(params[0]), "<>")
+			}
 		}
 	}
 
-	// close body
-	r.Body.Close()
+	return ""
+}
+
+// checks a webhook signature header is a sha256 hmac of the body
+func validWebhookSignature(secret string, signature string, body []byte) bool {
+	if len(secret) == 0 || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
 }
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// signs a body the way twitch signs webhook notifications
+func signWebhook(secret string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	for _, part := range parts {
+		mac.Write([]byte(part))
+	}
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidWebhookSignature(t *testing.T) {
+	body := `{"data":[{"from_id":"2","to_id":"1"}]}`
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		body      string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", signature: signWebhook("secret", body), body: body, want: true},
+		{name: "wrong secret", secret: "secret", signature: signWebhook("other", body), body: body, want: false},
+		{name: "changed body", secret: "secret", signature: signWebhook("secret", body), body: body + " ", want: false},
+		{name: "no secret", secret: "", signature: signWebhook("", body), body: body, want: false},
+		{name: "no prefix", secret: "secret", signature: signWebhook("secret", body)[len("sha256="):], body: body, want: false},
+		{name: "sha1", secret: "secret", signature: "sha1=" + signWebhook("secret", body)[len("sha256="):], body: body, want: false},
+		{name: "bad hex", secret: "secret", signature: "sha256=zz", body: body, want: false},
+		{name: "no signature", secret: "secret", signature: "", body: body, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validWebhookSignature(tt.secret, tt.signature, []byte(tt.body)); got != tt.want {
+				t.Errorf("validWebhookSignature() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -65,5 +65,8 @@ func (api *API) handleFollowersGet(w http.ResponseWriter, r *http.Request) {
 		log.Printf("[ERROR] get followers: %s", err)
 	}
 
+	// add user profiles
+	api.embedFollowerUsers(followers)
+
 	api.handleSuccess(w, followers)
 }
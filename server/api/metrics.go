@@ -0,0 +1,23 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// handleMetrics
+func (api *API) handleMetrics() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleMetricsGet(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleMetricsGet
+func (api *API) handleMetricsGet(w http.ResponseWriter, r *http.Request) {
+	api.handleSuccess(w, api.twitch.Metrics().Counters())
+}
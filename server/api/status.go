@@ -0,0 +1,34 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
+)
+
+// StatusResp reports the state of the twitch connections.
+type StatusResp struct {
+	PubSub             []*twitch.PUBSUBConnStatus  `json:"pubsub"`
+	PubSubBrokenTopics []*twitch.PUBSUBBrokenTopic `json:"pubsub_broken_topics"`
+}
+
+// handleStatus
+func (api *API) handleStatus() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			api.handleStatusGet(w, r)
+		default:
+			api.handleError(w, 400, fmt.Errorf("method not allowed"))
+		}
+	})
+}
+
+// handleStatusGet
+func (api *API) handleStatusGet(w http.ResponseWriter, r *http.Request) {
+	api.handleSuccess(w, &StatusResp{
+		PubSub:             api.twitch.PubSubStatus(),
+		PubSubBrokenTopics: api.twitch.PubSubBrokenTopics(),
+	})
+}
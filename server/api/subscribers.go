@@ -60,11 +60,25 @@ func (api *API) handleSubscribersGet(w http.ResponseWriter, r *http.Request) {
 		offset = offsetDefault
 	}
 
+	// get subscriber summaries instead of subscription events
+	if v.Get("view") == "summaries" {
+		summaries, err := api.database.GetSubscriberSummaries(channelID, latest, limit, offset)
+		if err != nil {
+			log.Printf("[ERROR] get subscriber summaries: %s", err)
+		}
+
+		api.handleSuccess(w, summaries)
+		return
+	}
+
 	// get subscribers
 	subscribers, err := api.database.GetSubscribers(channelID, latest, limit, offset)
 	if err != nil {
 		log.Printf("[ERROR] get subscribers: %s", err)
 	}
 
+	// add user profiles
+	api.embedSubscriberUsers(subscribers)
+
 	api.handleSuccess(w, subscribers)
 }
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"log"
+
+	database "github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+// returns the cached user profiles for the ids
+func (api *API) getUsers(ids []string) map[string]*database.User {
+	users, err := api.database.GetUsers(ids)
+	if err != nil {
+		log.Printf("[ERROR] get users: %s", err)
+	}
+
+	return users
+}
+
+// embeds cached user profiles in to followers
+func (api *API) embedFollowerUsers(followers []*database.Follower) {
+	ids := make([]string, 0, len(followers))
+	for _, follower := range followers {
+		ids = append(ids, follower.FollowerID)
+	}
+
+	users := api.getUsers(ids)
+	for _, follower := range followers {
+		if user, ok := users[follower.FollowerID]; ok {
+			follower.DisplayName = user.DisplayName
+			follower.ProfileImageURL = user.ProfileImageURL
+		}
+	}
+}
+
+// embeds cached user profiles in to subscribers
+func (api *API) embedSubscriberUsers(subscribers []*database.Subscriber) {
+	ids := make([]string, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		ids = append(ids, subscriber.SubscriberID)
+	}
+
+	users := api.getUsers(ids)
+	for _, subscriber := range subscribers {
+		if user, ok := users[subscriber.SubscriberID]; ok {
+			// keep the name twitch sent with the event
+			if len(subscriber.DisplayName) == 0 {
+				subscriber.DisplayName = user.DisplayName
+			}
+			subscriber.ProfileImageURL = user.ProfileImageURL
+		}
+	}
+}
+
+// embeds cached user profiles in to bit events
+func (api *API) embedBitUsers(bits []*database.Bit) {
+	ids := make([]string, 0, len(bits))
+	for _, bit := range bits {
+		ids = append(ids, bit.UserID)
+	}
+
+	users := api.getUsers(ids)
+	for _, bit := range bits {
+		if user, ok := users[bit.UserID]; ok {
+			bit.DisplayName = user.DisplayName
+			bit.ProfileImageURL = user.ProfileImageURL
+		}
+	}
+}
+
+// embeds cached user profiles in to gift events, leaving anonymous
+// gifters out
+func (api *API) embedGiftUsers(gifts []*database.Gift) {
+	ids := make([]string, 0, len(gifts))
+	for _, gift := range gifts {
+		if !gift.IsAnonymous && len(gift.GifterID) > 0 {
+			ids = append(ids, gift.GifterID)
+		}
+	}
+
+	users := api.getUsers(ids)
+	for _, gift := range gifts {
+		if gift.IsAnonymous {
+			continue
+		}
+		if user, ok := users[gift.GifterID]; ok {
+			gift.ProfileImageURL = user.ProfileImageURL
+		}
+	}
+}
+
+// embeds cached user profiles in to raid events
+func (api *API) embedRaidUsers(raids []*database.Raid) {
+	ids := make([]string, 0, len(raids))
+	for _, raid := range raids {
+		ids = append(ids, raid.RaiderID)
+	}
+
+	users := api.getUsers(ids)
+	for _, raid := range raids {
+		if user, ok := users[raid.RaiderID]; ok {
+			raid.ProfileImageURL = user.ProfileImageURL
+		}
+	}
+}
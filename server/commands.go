@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	config "github.com/codephobia/twitch-eos-thanks/server/config"
+	database "github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+	"github.com/codephobia/twitch-eos-thanks/server/metrics"
+	"github.com/codephobia/twitch-eos-thanks/server/twitch"
+)
+
+// runs a command by name
+func runCommand(name string, args []string) error {
+	switch name {
+	case "replay":
+		return runReplay(args)
+	case "rebuild-summaries":
+		return runRebuildSummaries()
+	default:
+		return fmt.Errorf("unknown command")
+	}
+}
+
+// replays journaled pub sub messages, e.g. to rebuild data lost
+// to a database outage or to reproduce a parsing bug
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	fromFlag := flags.String("from", "", "start of the window to replay, RFC3339")
+	toFlag := flags.String("to", "", "end of the window to replay, RFC3339, defaults to now")
+	dryRun := flags.Bool("dry-run", false, "decode the journaled frames without saving them")
+	flags.Parse(args)
+
+	// parse window
+	from, err := time.Parse(time.RFC3339, *fromFlag)
+	if err != nil {
+		return fmt.Errorf("invalid from: %s", err)
+	}
+	to := time.Now()
+	if len(*toFlag) > 0 {
+		if to, err = time.Parse(time.RFC3339, *toFlag); err != nil {
+			return fmt.Errorf("invalid to: %s", err)
+		}
+	}
+
+	// load config
+	c := config.NewConfig()
+	if err := c.Load(); err != nil {
+		return err
+	}
+
+	// init database
+	db := database.NewDatabase(c)
+	if err := db.Init(); err != nil {
+		return err
+	}
+
+	// replayed events go out to every sink, waiting for them to finish.
+	// A dry run only logs them.
+	m := metrics.NewMetrics()
+	var bus *events.Bus
+	if *dryRun {
+		bus = events.NewBus(m)
+		bus.Subscribe("dry-run", &dryRunSink{}, 0)
+	} else {
+		bus = newEventBus(c, db, m)
+	}
+	defer bus.Close()
+
+	// replay without connecting to twitch
+	t := twitch.NewTwitch(c, db, m, bus)
+	defer t.Close()
+	replayed, err := t.ReplayPubSub(from, to, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] replay: replayed %d messages from %s to %s", replayed, from.Format(time.RFC3339), to.Format(time.RFC3339))
+
+	return nil
+}
+
+// builds the subscriber summaries again from the saved subscription
+// events, e.g. after they were counted wrong
+func runRebuildSummaries() error {
+	// load config
+	c := config.NewConfig()
+	if err := c.Load(); err != nil {
+		return err
+	}
+
+	// init database
+	db := database.NewDatabase(c)
+	if err := db.Init(); err != nil {
+		return err
+	}
+
+	counted, err := db.RebuildSubscriberSummaries()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] rebuild-summaries: counted %d subscription events", counted)
+
+	return nil
+}
+
+// dryRunSink logs replayed events in place of saving them.
+type dryRunSink struct{}
+
+// HandleEvent logs an event.
+func (s *dryRunSink) HandleEvent(e events.Event) error {
+	log.Printf("[INFO] replay: %s event: channel [%s]: %s", e.Kind(), e.Metadata().ChannelID, e.Metadata().MessageID)
+	return nil
+}
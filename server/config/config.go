@@ -4,22 +4,86 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"log"
 	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/secrets"
 )
 
 const (
 	configFilePath = "./config.json"
+
+	// TransportPubSub listens for events on the legacy pub sub websocket.
+	TransportPubSub = "pubsub"
+	// TransportEventSub listens for events on the eventsub websocket.
+	TransportEventSub = "eventsub"
+
+	// DefaultTwitchOAuthURL is the base url of the twitch oauth endpoints.
+	DefaultTwitchOAuthURL = "https://id.twitch.tv/oauth2"
+	// DefaultTwitchAPIURL is the base url of the helix and kraken apis.
+	DefaultTwitchAPIURL = "https://api.twitch.tv"
+	// DefaultTwitchPubSubURL is the url of the pub sub websocket.
+	DefaultTwitchPubSubURL = "wss://pubsub-edge.twitch.tv"
+	// DefaultTwitchEventSubURL is the url of the eventsub websocket.
+	DefaultTwitchEventSubURL = "wss://eventsub.wss.twitch.tv/ws"
+	// DefaultPubSubJournalDays is how long journaled pub sub frames are kept.
+	DefaultPubSubJournalDays = 7
+
+	// SecretsPassphraseEnv is the environment variable holding the secret
+	// store passphrase. The key file is used when it isn't set.
+	SecretsPassphraseEnv = "TWITCH_EOS_THANKS_SECRETS_PASSPHRASE"
+
+	defaultSecretsPath    = "./secrets.json"
+	defaultSecretsKeyFile = "./secrets.key"
 )
 
 // Config stores the configuration file options.
 type Config struct {
-	TwitchClientID            string `json:"twitch_client_id"`
-	TwitchClientSecret        string `json:"twitch_client_secret"`
-	TwitchOAuthToken          string `json:"twitch_oauth_token"`
-	TwitchChannelID           string `json:"twitch_channel_id"`
-	TwitchChannelOAuthToken   string `json:"twitch_channel_oauth_token"`
-	TwitchChannelRefreshToken string `json:"twitch_channel_refresh_token"`
+	TwitchClientID     string `json:"twitch_client_id"`
+	TwitchClientSecret string `json:"-"`
+	TwitchOAuthToken   string `json:"-"`
+	TwitchTransport    string `json:"twitch_transport"`
+
+	// references to secrets kept in the secret store
+	TwitchClientSecretRef string `json:"twitch_client_secret_ref,omitempty"`
+	TwitchOAuthTokenRef   string `json:"twitch_oauth_token_ref,omitempty"`
+
+	// plaintext secrets, migrated in to the secret store on load
+	PlaintextTwitchClientSecret string `json:"twitch_client_secret,omitempty"`
+	PlaintextTwitchOAuthToken   string `json:"twitch_oauth_token,omitempty"`
+
+	// redirect uri registered on the twitch app for /auth/callback
+	TwitchRedirectURI string `json:"twitch_redirect_uri"`
+	// overrides the twitch oauth endpoints, e.g. to use a local stub
+	TwitchOAuthURL string `json:"twitch_oauth_url,omitempty"`
+	// overrides the twitch api, pub sub and eventsub urls, e.g. to use
+	// a local fake
+	TwitchAPIURL      string `json:"twitch_api_url,omitempty"`
+	TwitchPubSubURL   string `json:"twitch_pubsub_url,omitempty"`
+	TwitchEventSubURL string `json:"twitch_eventsub_url,omitempty"`
+
+	// public url of the /follow route, follow webhooks are off when empty
+	TwitchWebhookCallback string `json:"twitch_webhook_callback"`
+
+	// secret eventsub webhook notifications are signed with
+	TwitchEventSubSecret          string `json:"-"`
+	TwitchEventSubSecretRef       string `json:"twitch_eventsub_secret_ref,omitempty"`
+	PlaintextTwitchEventSubSecret string `json:"twitch_eventsub_secret,omitempty"`
+
+	// single channel options, migrated in to Channels on load
+	TwitchChannelID           string `json:"twitch_channel_id,omitempty"`
+	TwitchChannelOAuthToken   string `json:"twitch_channel_oauth_token,omitempty"`
+	TwitchChannelRefreshToken string `json:"twitch_channel_refresh_token,omitempty"`
+
+	Channels []*Channel `json:"channels"`
+
+	// minimum number of pub sub connections to spread topics across
+	PubSubConnections int `json:"pubsub_connections"`
+	// days journaled pub sub frames are kept for
+	PubSubJournalDays int `json:"pubsub_journal_days"`
 
 	MongoDBHost     string `json:"mongo_db_host"`
 	MongoDBPort     string `json:"mongo_db_port"`
@@ -27,6 +91,50 @@ type Config struct {
 
 	APIHost string `json:"api_host"`
 	APIPort string `json:"api_port"`
+
+	// urls every event is posted to as it's saved
+	EventWebhooks []string `json:"event_webhooks"`
+
+	// bearer token for the admin routes, which are off when empty
+	APIAdminToken          string `json:"-"`
+	APIAdminTokenRef       string `json:"api_admin_token_ref,omitempty"`
+	PlaintextAPIAdminToken string `json:"api_admin_token,omitempty"`
+
+	SecretsPath    string `json:"secrets_path"`
+	SecretsKeyFile string `json:"secrets_key_file"`
+
+	// guards the channels, which are changed and saved while
+	// the server is running
+	mu      sync.RWMutex
+	secrets *secrets.Store
+}
+
+// Channel is a twitch channel that events are ingested for.
+type Channel struct {
+	ID           string `json:"id"`
+	OAuthToken   string `json:"-"`
+	RefreshToken string `json:"-"`
+	Transport    string `json:"transport"`
+
+	// pub sub topics to listen for, the defaults when nil
+	Topics []string `json:"topics"`
+
+	// references to secrets kept in the secret store
+	OAuthTokenRef   string `json:"oauth_token_ref,omitempty"`
+	RefreshTokenRef string `json:"refresh_token_ref,omitempty"`
+
+	// plaintext secrets, migrated in to the secret store on load
+	PlaintextOAuthToken   string `json:"oauth_token,omitempty"`
+	PlaintextRefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// secretField ties an in memory secret to its store reference
+// and its plaintext config option.
+type secretField struct {
+	name      string
+	value     *string
+	ref       *string
+	plaintext *string
 }
 
 // NewConfig returns a new config.
@@ -46,12 +154,294 @@ func (c *Config) Load() error {
 		return fmt.Errorf("config decode: %s", err)
 	}
 
+	// move single channel options in to the channel list
+	if len(c.TwitchChannelID) > 0 {
+		c.Channels = append(c.Channels, &Channel{
+			ID:                    c.TwitchChannelID,
+			PlaintextOAuthToken:   c.TwitchChannelOAuthToken,
+			PlaintextRefreshToken: c.TwitchChannelRefreshToken,
+		})
+
+		c.TwitchChannelID = ""
+		c.TwitchChannelOAuthToken = ""
+		c.TwitchChannelRefreshToken = ""
+	}
+
+	// default channel transports
+	for _, channel := range c.Channels {
+		if len(channel.Transport) == 0 {
+			channel.Transport = c.TwitchTransport
+		}
+		if len(channel.Transport) == 0 {
+			channel.Transport = TransportPubSub
+		}
+	}
+
+	// default secret store files
+	if len(c.SecretsPath) == 0 {
+		c.SecretsPath = defaultSecretsPath
+	}
+	if len(c.SecretsKeyFile) == 0 {
+		c.SecretsKeyFile = defaultSecretsKeyFile
+	}
+
+	// open the secret store
+	c.secrets = secrets.NewStore(c.SecretsPath)
+	if err := c.secrets.Open(c.SecretsKeyFile, os.Getenv(SecretsPassphraseEnv)); err != nil {
+		return fmt.Errorf("config secrets: %s", err)
+	}
+
+	migrated := false
+	for _, field := range c.secretFields() {
+		// read secret from the store
+		if len(*field.ref) > 0 {
+			value, ok := c.secrets.Get(*field.ref)
+			if !ok {
+				return fmt.Errorf("config secrets: missing secret: %s", *field.ref)
+			}
+			*field.value = value
+		}
+
+		// move plaintext secret in to the store
+		if len(*field.plaintext) > 0 {
+			*field.value = *field.plaintext
+			*field.plaintext = ""
+			migrated = true
+		}
+	}
+
+	// save the migrated secrets so the plaintext is removed
+	if migrated {
+		log.Printf("[INFO] config: moving plaintext secrets in to the secret store")
+		return c.Save()
+	}
+
+	return nil
+}
+
+// returns the secrets held by the config
+func (c *Config) secretFields() []*secretField {
+	fields := []*secretField{
+		{
+			name:      "twitch_client_secret",
+			value:     &c.TwitchClientSecret,
+			ref:       &c.TwitchClientSecretRef,
+			plaintext: &c.PlaintextTwitchClientSecret,
+		},
+		{
+			name:      "twitch_oauth_token",
+			value:     &c.TwitchOAuthToken,
+			ref:       &c.TwitchOAuthTokenRef,
+			plaintext: &c.PlaintextTwitchOAuthToken,
+		},
+		{
+			name:      "twitch_eventsub_secret",
+			value:     &c.TwitchEventSubSecret,
+			ref:       &c.TwitchEventSubSecretRef,
+			plaintext: &c.PlaintextTwitchEventSubSecret,
+		},
+		{
+			name:      "api_admin_token",
+			value:     &c.APIAdminToken,
+			ref:       &c.APIAdminTokenRef,
+			plaintext: &c.PlaintextAPIAdminToken,
+		},
+	}
+
+	for _, channel := range c.Channels {
+		fields = append(fields,
+			&secretField{
+				name:      "channels/" + channel.ID + "/oauth_token",
+				value:     &channel.OAuthToken,
+				ref:       &channel.OAuthTokenRef,
+				plaintext: &channel.PlaintextOAuthToken,
+			},
+			&secretField{
+				name:      "channels/" + channel.ID + "/refresh_token",
+				value:     &channel.RefreshToken,
+				ref:       &channel.RefreshTokenRef,
+				plaintext: &channel.PlaintextRefreshToken,
+			},
+		)
+	}
+
+	return fields
+}
+
+// Channel returns the channel with the given id.
+func (c *Config) Channel(id string) (*Channel, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, channel := range c.Channels {
+		if channel.ID == id {
+			return channel, true
+		}
+	}
+
+	return nil, false
+}
+
+// ChannelList returns a copy of the channel list, safe to range over
+// while channels are added.
+func (c *Config) ChannelList() []*Channel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	channels := make([]*Channel, len(c.Channels))
+	copy(channels, c.Channels)
+
+	return channels
+}
+
+// AddChannel adds a channel, returning the channel already configured
+// with the same id instead if there is one.
+func (c *Config) AddChannel(channel *Channel) *Channel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.Channels {
+		if existing.ID == channel.ID {
+			return existing
+		}
+	}
+
+	c.Channels = append(c.Channels, channel)
+
+	return channel
+}
+
+// ChannelTokens returns the access and refresh tokens of a channel.
+func (c *Config) ChannelTokens(channel *Channel) (string, string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return channel.OAuthToken, channel.RefreshToken
+}
+
+// SetChannelTokens replaces the tokens of a channel and saves the config.
+// The tokens are kept in memory even if saving fails, since twitch may
+// have already rotated the old refresh token out.
+func (c *Config) SetChannelTokens(channel *Channel, oauthToken string, refreshToken string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	channel.OAuthToken = oauthToken
+	channel.RefreshToken = refreshToken
+
+	return c.save()
+}
+
+// ChannelTopics returns a copy of the pub sub topics of a channel, nil
+// if it hasn't chosen any.
+func (c *Config) ChannelTopics(channel *Channel) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if channel.Topics == nil {
+		return nil
+	}
+
+	return append([]string{}, channel.Topics...)
+}
+
+// SetChannelTopics replaces the pub sub topics of a channel and saves
+// the config, keeping the old topics if saving fails.
+func (c *Config) SetChannelTopics(channel *Channel, topics []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := channel.Topics
+	channel.Topics = topics
+
+	if err := c.save(); err != nil {
+		channel.Topics = previous
+		return err
+	}
+
 	return nil
 }
 
+// OAuthURL returns the base url of the twitch oauth endpoints.
+func (c *Config) OAuthURL() string {
+	if len(c.TwitchOAuthURL) > 0 {
+		return strings.TrimSuffix(c.TwitchOAuthURL, "/")
+	}
+
+	return DefaultTwitchOAuthURL
+}
+
+// APIURL returns the base url of the helix and kraken apis.
+func (c *Config) APIURL() string {
+	if len(c.TwitchAPIURL) > 0 {
+		return strings.TrimSuffix(c.TwitchAPIURL, "/")
+	}
+
+	return DefaultTwitchAPIURL
+}
+
+// PubSubURL returns the url of the pub sub websocket.
+func (c *Config) PubSubURL() string {
+	if len(c.TwitchPubSubURL) > 0 {
+		return c.TwitchPubSubURL
+	}
+
+	return DefaultTwitchPubSubURL
+}
+
+// EventSubURL returns the url of the eventsub websocket.
+func (c *Config) EventSubURL() string {
+	if len(c.TwitchEventSubURL) > 0 {
+		return c.TwitchEventSubURL
+	}
+
+	return DefaultTwitchEventSubURL
+}
+
+// PubSubJournalRetention returns how long journaled pub sub frames are kept.
+func (c *Config) PubSubJournalRetention() time.Duration {
+	days := c.PubSubJournalDays
+	if days <= 0 {
+		days = DefaultPubSubJournalDays
+	}
+
+	return time.Duration(days) * 24 * time.Hour
+}
+
 // Save saves the current in memory config values to
 // the configuration json file.
 func (c *Config) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.save()
+}
+
+// saves the config, must be called with c.mu held
+func (c *Config) save() error {
+	// update secrets in the secret store
+	for _, field := range c.secretFields() {
+		// remove cleared secrets, so load doesn't look for them
+		if len(*field.value) == 0 {
+			if len(*field.ref) > 0 {
+				c.secrets.Set(*field.ref, "")
+				*field.ref = ""
+			}
+			continue
+		}
+
+		if len(*field.ref) == 0 {
+			*field.ref = field.name
+		}
+
+		c.secrets.Set(*field.ref, *field.value)
+	}
+
+	// save secrets before the config references them
+	if err := c.secrets.Save(); err != nil {
+		return err
+	}
+
 	// marshal config
 	configJSON, err := json.Marshal(c)
 	if err != nil {
@@ -65,5 +455,5 @@ func (c *Config) Save() error {
 	}
 
 	// write updates to file
-	return ioutil.WriteFile(configFilePath, prettyJSON.Bytes(), 0644)
+	return secrets.WriteFile(configFilePath, prettyJSON.Bytes(), 0600)
 }
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Baseline is what helix last reported for a channel while it was
+// listened to on pub sub, kept so a backfill survives a restart.
+type Baseline struct {
+	ChannelID     string                 `bson:"_id" json:"channel_id"`
+	Subscriptions *BaselineSubscriptions `bson:"subscriptions,omitempty" json:"subscriptions,omitempty"`
+	Bits          *BaselineBits          `bson:"bits,omitempty" json:"bits,omitempty"`
+
+	// when the channel was last known to be listened to
+	Listened time.Time `bson:"listened,omitempty" json:"listened,omitempty"`
+}
+
+// BaselineSubscriptions are the subscriptions of a channel when they
+// were read.
+type BaselineSubscriptions struct {
+	At   time.Time               `bson:"at" json:"at"`
+	Subs []*BaselineSubscription `bson:"subs" json:"subs"`
+}
+
+// BaselineSubscription is a subscription in a baseline.
+type BaselineSubscription struct {
+	UserID     string `bson:"user_id" json:"user_id"`
+	UserLogin  string `bson:"user_login" json:"user_login"`
+	UserName   string `bson:"user_name" json:"user_name"`
+	GifterID   string `bson:"gifter_id,omitempty" json:"gifter_id,omitempty"`
+	GifterName string `bson:"gifter_name,omitempty" json:"gifter_name,omitempty"`
+	IsGift     bool   `bson:"is_gift" json:"is_gift"`
+	Tier       string `bson:"tier" json:"tier"`
+	PlanName   string `bson:"plan_name" json:"plan_name"`
+}
+
+// BaselineBits is the all time bits leaderboard of a channel when it
+// was read.
+type BaselineBits struct {
+	At       time.Time        `bson:"at" json:"at"`
+	Scores   []*BaselineScore `bson:"scores" json:"scores"`
+	Complete bool             `bson:"complete" json:"complete"`
+}
+
+// BaselineScore is a cheerer on a baseline leaderboard.
+type BaselineScore struct {
+	UserID    string `bson:"user_id" json:"user_id"`
+	UserLogin string `bson:"user_login" json:"user_login"`
+	UserName  string `bson:"user_name" json:"user_name"`
+	Score     int    `bson:"score" json:"score"`
+}
+
+// GetBaseline returns the baseline of a channel, or nil without one.
+func (db *Database) GetBaseline(channelID string) (*Baseline, error) {
+	var baseline Baseline
+	if err := db.baselines.FindId(channelID).One(&baseline); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to get baseline: %s", err)
+	}
+
+	return &baseline, nil
+}
+
+// SetBaseline replaces the parts of the baseline of a channel that were
+// read.
+func (db *Database) SetBaseline(b *Baseline) error {
+	update := bson.M{}
+	if b.Subscriptions != nil {
+		update["subscriptions"] = b.Subscriptions
+	}
+	if b.Bits != nil {
+		update["bits"] = b.Bits
+	}
+	if !b.Listened.IsZero() {
+		update["listened"] = b.Listened
+	}
+
+	if _, err := db.baselines.UpsertId(b.ChannelID, bson.M{
+		"$set": update,
+	}); err != nil {
+		return fmt.Errorf("unable to set baseline: %s", err)
+	}
+
+	return nil
+}
+
+// SetBaselineListened records when a channel was last listened to.
+func (db *Database) SetBaselineListened(channelID string, t time.Time) error {
+	if _, err := db.baselines.UpsertId(channelID, bson.M{
+		"$set": bson.M{
+			"listened": t,
+		},
+	}); err != nil {
+		return fmt.Errorf("unable to set baseline listened: %s", err)
+	}
+
+	return nil
+}
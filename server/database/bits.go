@@ -10,6 +10,7 @@ import (
 // Bit is a bit pub sub message from twitch.
 type Bit struct {
 	ID               bson.ObjectId     `bson:"_id,omitempty" json:"ID,omitempty"`
+	MessageID        string            `bson:"message_id,omitempty" json:"message_id,omitempty"`
 	UserName         string            `bson:"user_name" json:"user_name"`
 	ChannelName      string            `bson:"channel_name" json:"channel_name"`
 	UserID           string            `bson:"user_id" json:"user_id"`
@@ -20,6 +21,16 @@ type Bit struct {
 	TotalBitsUsed    int               `bson:"total_bits_used" json:"total_bits_used"`
 	Context          string            `bson:"context" json:"context"`
 	BadgeEntitlement *BadgeEntitlement `bson:"badge_entitlement" json:"badge_entitlement"`
+
+	// set when recovered from helix after pub sub was down
+	Backfilled bool `bson:"backfilled,omitempty" json:"backfilled,omitempty"`
+	// start of the gap a backfilled event happened in, it's
+	// timestamped when it was recovered
+	BackfilledFrom time.Time `bson:"backfilled_from,omitempty" json:"backfilled_from,omitempty"`
+
+	// embedded from the user profile cache
+	DisplayName     string `bson:"-" json:"display_name,omitempty"`
+	ProfileImageURL string `bson:"-" json:"profile_image_url,omitempty"`
 }
 
 // BadgeEntitlement contains meta data for the user badge on a Bit.
@@ -31,7 +42,31 @@ type BadgeEntitlement struct {
 // AddBit adds a bit event to the database.
 func (db *Database) AddBit(b *Bit) error {
 	// insert new bit event
-	return db.bits.Insert(b)
+	return insertEvent(db.bits, b)
+}
+
+// GetBitsUsedSince returns the bits a user cheered in the channel
+// since the given time.
+func (db *Database) GetBitsUsedSince(channelID string, userID string, since time.Time) (int, error) {
+	iter := db.bits.Find(bson.M{
+		"channel_id": channelID,
+		"user_id":    userID,
+		"timestamp": bson.M{
+			"$gte": since,
+		},
+	}).Select(bson.M{"bits_used": 1}).Iter()
+
+	total := 0
+	var bit Bit
+	for iter.Next(&bit) {
+		total += bit.BitsUsed
+	}
+
+	if err := iter.Close(); err != nil {
+		return 0, fmt.Errorf("unable to get bits used: %s", err)
+	}
+
+	return total, nil
 }
 
 // GetBits returns a slice of bit events.
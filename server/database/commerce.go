@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Commerce is a commerce pub sub message from twitch.
+type Commerce struct {
+	ID              bson.ObjectId    `bson:"_id,omitempty" json:"ID,omitempty"`
+	MessageID       string           `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	UserName        string           `bson:"user_name" json:"user_name"`
+	DisplayName     string           `bson:"display_name" json:"display_name"`
+	ChannelName     string           `bson:"channel_name" json:"channel_name"`
+	UserID          string           `bson:"user_id" json:"user_id"`
+	ChannelID       string           `bson:"channel_id" json:"channel_id"`
+	Time            time.Time        `bson:"timestamp" json:"timestamp"`
+	ItemImageURL    string           `bson:"item_image_url" json:"item_image_url"`
+	ItemDescription string           `bson:"item_description" json:"item_description"`
+	SupportsChannel bool             `bson:"supports_channel" json:"supports_channel"`
+	PurchaseMessage *PurchaseMessage `bson:"purchase_message" json:"purchase_message"`
+}
+
+// PurchaseMessage is the message sent when a user makes a purchase.
+type PurchaseMessage struct {
+	Message string             `bson:"message" json:"message"`
+	Emotes  []*SubMessageEmote `bson:"emotes" json:"emotes"`
+}
+
+// AddCommerce adds a commerce event to the database.
+func (db *Database) AddCommerce(c *Commerce) error {
+	// insert new commerce event
+	return insertEvent(db.commerce, c)
+}
+
+// GetCommerce returns a slice of commerce events.
+func (db *Database) GetCommerce(channelID string, latest int64, limit int, offset int) ([]*Commerce, error) {
+	commerce := make([]*Commerce, 0)
+
+	// convert latest to time
+	ltUnix := latest / (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	ltNano := latest % (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	lt := time.Unix(ltUnix, ltNano)
+
+	// build query
+	query := db.commerce.Find(bson.M{
+		"channel_id": channelID,
+		"timestamp": bson.M{
+			"$gt": lt,
+		},
+	})
+
+	// add filters
+	query.Limit(limit).Skip(offset).Sort("-timestamp")
+
+	// get commerce events
+	err := query.All(&commerce)
+	if err != nil {
+		return commerce, fmt.Errorf("unable to get commerce: %s", err)
+	}
+
+	return commerce, nil
+}
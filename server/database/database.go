@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"log"
 	"strings"
 
 	mgo "gopkg.in/mgo.v2"
@@ -12,18 +13,42 @@ import (
 const (
 	collectionFollowers   = "followers"
 	collectionSubscribers = "subscribers"
+	collectionSummaries   = "subscriber_summaries"
 	collectionBits        = "bits"
+	collectionCommerce    = "commerce"
+	collectionRedemptions = "redemptions"
+	collectionWebhooks    = "webhooks"
+	collectionRevocations = "eventsub_revocations"
+	collectionUsers       = "users"
+	collectionGifts       = "gifts"
+	collectionJournal     = "pubsub_journal"
+	collectionDeadLetters = "dead_letters"
+	collectionGaps        = "pubsub_gaps"
+	collectionRaids       = "raids"
+	collectionBaselines   = "pubsub_baselines"
 )
 
 // Database handles the MongoDB connection.
 type Database struct {
 	config *config.Config
 
-	session     *mgo.Session
-	database    *mgo.Database
-	followers   *mgo.Collection
-	subscribers *mgo.Collection
-	bits        *mgo.Collection
+	session             *mgo.Session
+	database            *mgo.Database
+	followers           *mgo.Collection
+	subscribers         *mgo.Collection
+	subscriberSummaries *mgo.Collection
+	bits                *mgo.Collection
+	commerce            *mgo.Collection
+	redemptions         *mgo.Collection
+	gifts               *mgo.Collection
+	webhooks            *mgo.Collection
+	revocations         *mgo.Collection
+	users               *mgo.Collection
+	journal             *mgo.Collection
+	deadLetters         *mgo.Collection
+	gaps                *mgo.Collection
+	raids               *mgo.Collection
+	baselines           *mgo.Collection
 }
 
 // NewDatabase returns a new database.
@@ -62,21 +87,150 @@ func (db *Database) initDatabase() {
 	// subscribers
 	db.initSubscribers()
 
+	// gifts
+	db.initGifts()
+
 	// bits
 	db.initBits()
+
+	// commerce
+	db.initCommerce()
+
+	// redemptions
+	db.initRedemptions()
+
+	// raids
+	db.initRaids()
+
+	// webhooks
+	db.initWebhooks()
+
+	// eventsub revocations
+	db.initRevocations()
+
+	// users
+	db.initUsers()
+
+	// pub sub journal
+	db.initJournal()
+
+	// dead letters
+	db.initDeadLetters()
+
+	// pub sub gaps
+	db.initGaps()
+
+	// backfill baselines
+	db.initBaselines()
 }
 
 // init followers collection
 func (db *Database) initFollowers() {
 	db.followers = db.database.C(collectionFollowers)
+
+	ensureMessageIDIndex(db.followers)
 }
 
 // init subscribers collection
 func (db *Database) initSubscribers() {
 	db.subscribers = db.database.C(collectionSubscribers)
+	db.subscriberSummaries = db.database.C(collectionSummaries)
+
+	ensureMessageIDIndex(db.subscribers)
+
+	// one summary per subscriber, so summaries are upserted safely
+	if err := db.subscriberSummaries.EnsureIndex(mgo.Index{
+		Key:    []string{"channel_id", "subscriber_id"},
+		Unique: true,
+	}); err != nil {
+		log.Printf("[ERROR] database: subscriber summaries index: %s", err)
+	}
+}
+
+// init gifts collection
+func (db *Database) initGifts() {
+	db.gifts = db.database.C(collectionGifts)
+
+	ensureMessageIDIndex(db.gifts)
 }
 
 // init bits collection
 func (db *Database) initBits() {
 	db.bits = db.database.C(collectionBits)
+
+	ensureMessageIDIndex(db.bits)
+}
+
+// init commerce collection
+func (db *Database) initCommerce() {
+	db.commerce = db.database.C(collectionCommerce)
+
+	ensureMessageIDIndex(db.commerce)
+}
+
+// init redemptions collection
+func (db *Database) initRedemptions() {
+	db.redemptions = db.database.C(collectionRedemptions)
+
+	ensureMessageIDIndex(db.redemptions)
+}
+
+// init raids collection
+func (db *Database) initRaids() {
+	db.raids = db.database.C(collectionRaids)
+
+	ensureMessageIDIndex(db.raids)
+}
+
+// init webhooks collection
+func (db *Database) initWebhooks() {
+	db.webhooks = db.database.C(collectionWebhooks)
+}
+
+// init eventsub revocations collection
+func (db *Database) initRevocations() {
+	db.revocations = db.database.C(collectionRevocations)
+}
+
+// init users collection
+func (db *Database) initUsers() {
+	db.users = db.database.C(collectionUsers)
+}
+
+// init pub sub journal collection
+func (db *Database) initJournal() {
+	db.journal = db.database.C(collectionJournal)
+
+	// replays read the journal by receive time, which also expires
+	// frames once they're past the retention
+	index := mgo.Index{
+		Key:         []string{"received_at"},
+		ExpireAfter: db.config.PubSubJournalRetention(),
+	}
+	if err := db.journal.EnsureIndex(index); err != nil {
+		// older versions made the index without an expiry, and it
+		// can't be changed in place
+		if dropErr := db.journal.DropIndex("received_at"); dropErr != nil {
+			log.Printf("[ERROR] database: journal index: %s", err)
+			return
+		}
+		if err := db.journal.EnsureIndex(index); err != nil {
+			log.Printf("[ERROR] database: journal index: %s", err)
+		}
+	}
+}
+
+// init dead letters collection
+func (db *Database) initDeadLetters() {
+	db.deadLetters = db.database.C(collectionDeadLetters)
+}
+
+// init pub sub gaps collection
+func (db *Database) initGaps() {
+	db.gaps = db.database.C(collectionGaps)
+}
+
+// init backfill baselines collection
+func (db *Database) initBaselines() {
+	db.baselines = db.database.C(collectionBaselines)
 }
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// dead letter sources
+const (
+	DeadLetterSourcePubSub   = "pubsub"
+	DeadLetterSourceEventSub = "eventsub"
+)
+
+// ErrDeadLetterNotFound is returned for a dead letter that doesn't exist.
+var ErrDeadLetterNotFound = fmt.Errorf("dead letter not found")
+
+// DeadLetter is an event that couldn't be decoded or saved, kept so it
+// can be retried instead of lost.
+type DeadLetter struct {
+	ID          bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	Source      string        `bson:"source" json:"source"`
+	Topic       string        `bson:"topic" json:"topic"`
+	Payload     string        `bson:"payload" json:"payload"`
+	MessageID   string        `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	SentAt      string        `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+	Error       string        `bson:"error" json:"error"`
+	Attempts    int           `bson:"attempts" json:"attempts"`
+	Timestamp   time.Time     `bson:"timestamp" json:"timestamp"`
+	LastAttempt time.Time     `bson:"last_attempt" json:"last_attempt"`
+}
+
+// AddDeadLetter adds a dead letter to the database.
+func (db *Database) AddDeadLetter(d *DeadLetter) error {
+	if err := db.deadLetters.Insert(d); err != nil {
+		// already saved by an earlier attempt
+		if mgo.IsDup(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to add dead letter: %s", err)
+	}
+
+	return nil
+}
+
+// GetDeadLetters returns a slice of dead letters, oldest first.
+func (db *Database) GetDeadLetters(limit int, offset int) ([]*DeadLetter, error) {
+	deadLetters := make([]*DeadLetter, 0)
+
+	// get dead letters
+	err := db.deadLetters.Find(nil).Sort("timestamp", "_id").Limit(limit).Skip(offset).All(&deadLetters)
+	if err != nil {
+		return deadLetters, fmt.Errorf("unable to get dead letters: %s", err)
+	}
+
+	return deadLetters, nil
+}
+
+// GetDeadLetter returns a dead letter by id.
+func (db *Database) GetDeadLetter(id string) (*DeadLetter, error) {
+	if !bson.IsObjectIdHex(id) {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	var deadLetter DeadLetter
+	if err := db.deadLetters.FindId(bson.ObjectIdHex(id)).One(&deadLetter); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, ErrDeadLetterNotFound
+		}
+		return nil, fmt.Errorf("unable to get dead letter: %s", err)
+	}
+
+	return &deadLetter, nil
+}
+
+// UpdateDeadLetterAttempt records a failed retry of a dead letter.
+func (db *Database) UpdateDeadLetterAttempt(id bson.ObjectId, attemptErr string) error {
+	if err := db.deadLetters.UpdateId(id, bson.M{
+		"$set": bson.M{
+			"error":        attemptErr,
+			"last_attempt": time.Now(),
+		},
+		"$inc": bson.M{
+			"attempts": 1,
+		},
+	}); err != nil {
+		return fmt.Errorf("unable to update dead letter: %s", err)
+	}
+
+	return nil
+}
+
+// RemoveDeadLetter removes a dead letter by id.
+func (db *Database) RemoveDeadLetter(id string) error {
+	if !bson.IsObjectIdHex(id) {
+		return ErrDeadLetterNotFound
+	}
+
+	if err := db.deadLetters.RemoveId(bson.ObjectIdHex(id)); err != nil {
+		if err == mgo.ErrNotFound {
+			return ErrDeadLetterNotFound
+		}
+		return fmt.Errorf("unable to remove dead letter: %s", err)
+	}
+
+	return nil
+}
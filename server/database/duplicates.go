@@ -0,0 +1,37 @@
+package database
+
+import (
+	"fmt"
+	"log"
+
+	mgo "gopkg.in/mgo.v2"
+)
+
+// ErrDuplicate is returned for an event that was already saved, found
+// by its twitch message id.
+var ErrDuplicate = fmt.Errorf("duplicate event")
+
+// events with a twitch message id are only saved once, older events
+// without one are left out of the index
+func ensureMessageIDIndex(c *mgo.Collection) {
+	if err := c.EnsureIndex(mgo.Index{
+		Key:    []string{"message_id"},
+		Unique: true,
+		Sparse: true,
+	}); err != nil {
+		log.Printf("[ERROR] database: %s message id index: %s", c.Name, err)
+	}
+}
+
+// inserts an event, returning ErrDuplicate if its message id was
+// already saved
+func insertEvent(c *mgo.Collection, event interface{}) error {
+	if err := c.Insert(event); err != nil {
+		if mgo.IsDup(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	return nil
+}
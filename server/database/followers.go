@@ -4,55 +4,118 @@ import (
 	"fmt"
 	"time"
 
+	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
 // Follower is a twitch follower.
 type Follower struct {
 	ID         bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	MessageID  string        `bson:"message_id,omitempty" json:"message_id,omitempty"`
 	ChannelID  string        `bson:"channel_id,omitempty" json:"channelID,omitempty"`
 	FollowerID string        `bson:"follower_id,omitempty" json:"followerID,omitempty"`
 	Timestamp  time.Time     `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
+
+	// set once reconciliation finds the follower left
+	UnfollowedAt time.Time `bson:"unfollowed_at,omitempty" json:"unfollowed_at,omitempty"`
+
+	// set when recovered from helix after pub sub was down
+	Backfilled bool `bson:"backfilled,omitempty" json:"backfilled,omitempty"`
+	// start of the gap a backfilled event happened in, it's
+	// timestamped when it was recovered
+	BackfilledFrom time.Time `bson:"backfilled_from,omitempty" json:"backfilled_from,omitempty"`
+
+	// embedded from the user profile cache
+	DisplayName     string `bson:"-" json:"display_name,omitempty"`
+	ProfileImageURL string `bson:"-" json:"profile_image_url,omitempty"`
 }
 
 // AddFollower adds a follower to the database.
 func (db *Database) AddFollower(f *Follower) error {
 	// check if follower already exists
-	followed, err := db.hasFollower(f.ChannelID, f.FollowerID)
-	if err != nil {
+	var existing Follower
+	err := db.followers.Find(bson.M{
+		"channel_id":  f.ChannelID,
+		"follower_id": f.FollowerID,
+	}).One(&existing)
+	if err != nil && err != mgo.ErrNotFound {
 		return err
 	}
 
+	// insert new follower
+	if err == mgo.ErrNotFound {
+		return insertEvent(db.followers, f)
+	}
+
 	// skip adding follower to database if they are already following
-	if followed {
-		return fmt.Errorf("found duplicate follower [%s] for channel [%s]", f.FollowerID, f.ChannelID)
+	if existing.UnfollowedAt.IsZero() {
+		return ErrDuplicate
 	}
 
-	// insert new follower
-	return db.followers.Insert(f)
+	// follower came back, so follow them again
+	update := bson.M{
+		"timestamp": f.Timestamp,
+	}
+	if len(f.MessageID) > 0 {
+		update["message_id"] = f.MessageID
+	}
+
+	if err := db.followers.UpdateId(existing.ID, bson.M{
+		"$set": update,
+		"$unset": bson.M{
+			"unfollowed_at": "",
+		},
+	}); err != nil {
+		// the follow was already saved under its message id
+		if mgo.IsDup(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+
+	return nil
 }
 
-// check for follower
-func (db *Database) hasFollower(channelID string, followerID string) (bool, error) {
+// GetFollowerIDs returns the ids of everyone still following the channel.
+func (db *Database) GetFollowerIDs(channelID string) (map[string]bool, error) {
+	followerIDs := make(map[string]bool)
+
 	// build query
-	query := db.followers.Find(bson.M{
-		"channel_id":  channelID,
-		"follower_id": followerID,
-	})
+	iter := db.followers.Find(bson.M{
+		"channel_id": channelID,
+		"unfollowed_at": bson.M{
+			"$exists": false,
+		},
+	}).Select(bson.M{
+		"follower_id": 1,
+	}).Iter()
 
-	// get count
-	count, err := query.Count()
-	if err != nil {
-		return false, err
+	// collect follower ids
+	var follower Follower
+	for iter.Next(&follower) {
+		followerIDs[follower.FollowerID] = true
 	}
 
-	// return that we found a follower
-	if count > 0 {
-		return true, nil
+	if err := iter.Close(); err != nil {
+		return followerIDs, fmt.Errorf("unable to get follower ids: %s", err)
 	}
 
-	// return that we didn't find a follower
-	return false, nil
+	return followerIDs, nil
+}
+
+// MarkUnfollowed marks a follower as having left the channel.
+func (db *Database) MarkUnfollowed(channelID string, followerID string, t time.Time) error {
+	return db.followers.Update(bson.M{
+		"channel_id":  channelID,
+		"follower_id": followerID,
+		"unfollowed_at": bson.M{
+			"$exists": false,
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"unfollowed_at": t,
+		},
+	})
 }
 
 // HasFollowers checks for any followers for the channel in the database.
@@ -95,6 +158,9 @@ func (db *Database) GetFollowers(channelID string, latest int64, limit int, offs
 		"timestamp": bson.M{
 			"$gt": lt,
 		},
+		"unfollowed_at": bson.M{
+			"$exists": false,
+		},
 	})
 
 	// add filters
@@ -112,3 +178,37 @@ func (db *Database) GetFollowers(channelID string, latest int64, limit int, offs
 
 	return followers, nil
 }
+
+// GetUnfollowers returns a slice of followers that left the channel.
+func (db *Database) GetUnfollowers(channelID string, latest int64, limit int, offset int) ([]*Follower, error) {
+	unfollowers := make([]*Follower, 0)
+
+	// convert latest to time
+	ltUnix := latest / (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	ltNano := latest % (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	lt := time.Unix(ltUnix, ltNano)
+
+	// build query
+	query := db.followers.Find(bson.M{
+		"channel_id": channelID,
+		"unfollowed_at": bson.M{
+			"$gt": lt,
+		},
+	})
+
+	// add filters
+	query.Limit(limit).Skip(offset).Sort("-unfollowed_at").Select(bson.M{
+		"_id":           0,
+		"follower_id":   1,
+		"timestamp":     1,
+		"unfollowed_at": 1,
+	})
+
+	// get unfollowers
+	err := query.All(&unfollowers)
+	if err != nil {
+		return unfollowers, fmt.Errorf("unable to get unfollowers: %s", err)
+	}
+
+	return unfollowers, nil
+}
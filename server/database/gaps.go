@@ -0,0 +1,30 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Gap is a time a channel wasn't listened to on pub sub, with what was
+// backfilled from helix once it was back.
+type Gap struct {
+	ID            bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	ChannelID     string        `bson:"channel_id" json:"channel_id"`
+	From          time.Time     `bson:"from" json:"from"`
+	To            time.Time     `bson:"to" json:"to"`
+	Follows       int           `bson:"follows" json:"follows"`
+	Subscriptions int           `bson:"subscriptions" json:"subscriptions"`
+	Bits          int           `bson:"bits" json:"bits"`
+	Errors        []string      `bson:"errors,omitempty" json:"errors,omitempty"`
+}
+
+// AddGap adds a pub sub gap to the database.
+func (db *Database) AddGap(g *Gap) error {
+	if err := db.gaps.Insert(g); err != nil {
+		return fmt.Errorf("unable to add gap: %s", err)
+	}
+
+	return nil
+}
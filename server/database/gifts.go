@@ -0,0 +1,183 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+var (
+	// subs gifted by the same gifter within the window are bundled
+	// in to the same gift event
+	giftBundleWindow = 1 * time.Minute
+)
+
+// Gift is a gift subscription event, bundling every sub a
+// gifter gave away in a single community gift.
+type Gift struct {
+	ID            bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	MessageID     string        `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	ChannelID     string        `bson:"channel_id" json:"channel_id"`
+	GifterID      string        `bson:"gifter_id" json:"gifter_id"`
+	GifterName    string        `bson:"gifter_name" json:"gifter_name"`
+	IsAnonymous   bool          `bson:"is_anonymous" json:"is_anonymous"`
+	SubPlan       string        `bson:"sub_plan" json:"sub_plan"`
+	Count         int           `bson:"count" json:"count"`
+	Timestamp     time.Time     `bson:"timestamp" json:"timestamp"`
+	LastTimestamp time.Time     `bson:"last_timestamp" json:"last_timestamp"`
+	// set when twitch sent the count with the gift, otherwise it's
+	// counted as its recipients are bundled in to it
+	KnownCount bool `bson:"known_count,omitempty" json:"known_count,omitempty"`
+
+	// embedded from the user profile cache
+	ProfileImageURL string `bson:"-" json:"profile_image_url,omitempty"`
+}
+
+// AddGift adds a gift event with a known count to the database,
+// recipients that follow are bundled in to it.
+func (db *Database) AddGift(g *Gift) error {
+	if len(g.ID) == 0 {
+		g.ID = bson.NewObjectId()
+	}
+	g.LastTimestamp = g.Timestamp
+	g.KnownCount = true
+
+	// insert new gift event
+	return insertEvent(db.gifts, g)
+}
+
+// bundle a saved gifted subscriber in to a gift event, setting its gift
+// id and gifter
+func (db *Database) bundleGift(s *Subscriber) error {
+	if err := db.findGift(s); err != nil {
+		return err
+	}
+
+	// nothing to bundle in to
+	if len(s.GiftID) == 0 {
+		return nil
+	}
+
+	if err := db.subscribers.UpdateId(s.ID, bson.M{
+		"$set": bson.M{
+			"gift_id":      s.GiftID,
+			"gifter_id":    s.GifterID,
+			"gifter_name":  s.GifterName,
+			"is_anonymous": s.IsAnonymous,
+		},
+	}); err != nil {
+		return fmt.Errorf("unable to set gift: %s", err)
+	}
+
+	return nil
+}
+
+// finds or starts the gift event a gifted subscriber belongs to,
+// counting the subscriber in it
+func (db *Database) findGift(s *Subscriber) error {
+	since := s.Timestamp.Add(-giftBundleWindow)
+
+	// recipients without a gifter belong to the latest gift event
+	if len(s.GifterID) == 0 && !s.IsAnonymous {
+		var gift Gift
+		err := db.gifts.Find(bson.M{
+			"channel_id": s.ChannelID,
+			"sub_plan":   s.SubPlan,
+			"last_timestamp": bson.M{
+				"$gte": since,
+			},
+		}).Sort("-last_timestamp").One(&gift)
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to find gift: %s", err)
+		}
+
+		// credit the gifter from the gift event
+		s.GiftID = gift.ID.Hex()
+		s.GifterID = gift.GifterID
+		s.GifterName = gift.GifterName
+		s.IsAnonymous = gift.IsAnonymous
+		return nil
+	}
+
+	// add to the gifter's open gift event
+	var gift Gift
+	err := db.gifts.Find(bson.M{
+		"channel_id":   s.ChannelID,
+		"gifter_id":    s.GifterID,
+		"is_anonymous": s.IsAnonymous,
+		"sub_plan":     s.SubPlan,
+		"last_timestamp": bson.M{
+			"$gte": since,
+		},
+	}).Sort("-last_timestamp").One(&gift)
+	if err == nil {
+		update := bson.M{
+			"$set": bson.M{"last_timestamp": s.Timestamp},
+		}
+
+		// gifts twitch sent the count with already count the recipient
+		if !gift.KnownCount {
+			update["$inc"] = bson.M{"count": 1}
+		}
+
+		err = db.gifts.UpdateId(gift.ID, update)
+	}
+
+	// no open gift event, so start a new one
+	if err == mgo.ErrNotFound {
+		gift = Gift{
+			ID:            bson.NewObjectId(),
+			ChannelID:     s.ChannelID,
+			GifterID:      s.GifterID,
+			GifterName:    s.GifterName,
+			IsAnonymous:   s.IsAnonymous,
+			SubPlan:       s.SubPlan,
+			Count:         1,
+			Timestamp:     s.Timestamp,
+			LastTimestamp: s.Timestamp,
+		}
+		err = db.gifts.Insert(&gift)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to bundle gift: %s", err)
+	}
+
+	s.GiftID = gift.ID.Hex()
+	return nil
+}
+
+// GetGifts returns a slice of gift events, newest first. Latest is
+// compared to the last recipient bundled in to a gift, so gifts still
+// counting their recipients are returned again.
+func (db *Database) GetGifts(channelID string, latest int64, limit int, offset int) ([]*Gift, error) {
+	gifts := make([]*Gift, 0)
+
+	// convert latest to time
+	ltUnix := latest / (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	ltNano := latest % (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	lt := time.Unix(ltUnix, ltNano)
+
+	// build query
+	query := db.gifts.Find(bson.M{
+		"channel_id": channelID,
+		"last_timestamp": bson.M{
+			"$gt": lt,
+		},
+	})
+
+	// add filters
+	query.Limit(limit).Skip(offset).Sort("-last_timestamp")
+
+	// get gift events
+	err := query.All(&gifts)
+	if err != nil {
+		return gifts, fmt.Errorf("unable to get gifts: %s", err)
+	}
+
+	return gifts, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// JournalEntry is a raw frame received on a pub sub connection.
+type JournalEntry struct {
+	ID         bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	ConnID     int           `bson:"conn_id" json:"conn_id"`
+	ReceivedAt time.Time     `bson:"received_at" json:"received_at"`
+	Frame      string        `bson:"frame" json:"frame"`
+}
+
+// AddJournalEntry appends a frame to the pub sub journal.
+func (db *Database) AddJournalEntry(e *JournalEntry) error {
+	if err := db.journal.Insert(e); err != nil {
+		return fmt.Errorf("unable to add journal entry: %s", err)
+	}
+
+	return nil
+}
+
+// GetJournalEntries calls fn for each frame received within the
+// window, oldest first.
+func (db *Database) GetJournalEntries(from time.Time, to time.Time, fn func(*JournalEntry) error) error {
+	iter := db.journal.Find(bson.M{
+		"received_at": bson.M{
+			"$gte": from,
+			"$lt":  to,
+		},
+	}).Sort("received_at", "_id").Iter()
+
+	var entry JournalEntry
+	for iter.Next(&entry) {
+		if err := fn(&entry); err != nil {
+			iter.Close()
+			return err
+		}
+		entry = JournalEntry{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return fmt.Errorf("unable to get journal entries: %s", err)
+	}
+
+	return nil
+}
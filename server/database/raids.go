@@ -0,0 +1,95 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Raid is a broadcaster raiding the channel.
+type Raid struct {
+	ID         bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	MessageID  string        `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	ChannelID  string        `bson:"channel_id" json:"channel_id"`
+	RaiderID   string        `bson:"raider_id" json:"raider_id"`
+	RaiderName string        `bson:"raider_name" json:"raider_name"`
+	Viewers    int           `bson:"viewers" json:"viewers"`
+	Timestamp  time.Time     `bson:"timestamp" json:"timestamp"`
+
+	// embedded from the user profile cache
+	ProfileImageURL string `bson:"-" json:"profile_image_url,omitempty"`
+}
+
+// AddRaid adds a raid event to the database.
+func (db *Database) AddRaid(r *Raid) error {
+	// insert new raid event
+	return insertEvent(db.raids, r)
+}
+
+// GetRaidFollowerIDs returns the ids of raiders that have a follower row
+// written by their raid, which older versions saved raids as. The row
+// has the raid timestamp rather than a follow time.
+func (db *Database) GetRaidFollowerIDs(channelID string) (map[string]bool, error) {
+	raidFollowerIDs := make(map[string]bool)
+
+	// build query
+	iter := db.raids.Find(bson.M{
+		"channel_id": channelID,
+	}).Select(bson.M{
+		"raider_id": 1,
+		"timestamp": 1,
+	}).Iter()
+
+	// check each raid for a follower row written by it
+	var raid Raid
+	for iter.Next(&raid) {
+		n, err := db.followers.Find(bson.M{
+			"channel_id":  channelID,
+			"follower_id": raid.RaiderID,
+			"timestamp":   raid.Timestamp,
+		}).Count()
+		if err != nil {
+			iter.Close()
+			return raidFollowerIDs, fmt.Errorf("unable to find raid follower: %s", err)
+		}
+		if n > 0 {
+			raidFollowerIDs[raid.RaiderID] = true
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return raidFollowerIDs, fmt.Errorf("unable to get raids: %s", err)
+	}
+
+	return raidFollowerIDs, nil
+}
+
+// GetRaids returns a slice of raid events.
+func (db *Database) GetRaids(channelID string, latest int64, limit int, offset int) ([]*Raid, error) {
+	raids := make([]*Raid, 0)
+
+	// convert latest to time
+	ltUnix := latest / (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	ltNano := latest % (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	lt := time.Unix(ltUnix, ltNano)
+
+	// build query
+	query := db.raids.Find(bson.M{
+		"channel_id": channelID,
+		"timestamp": bson.M{
+			"$gt": lt,
+		},
+	})
+
+	// add filters
+	query.Limit(limit).Skip(offset).Sort("-timestamp")
+
+	// get raid events
+	err := query.All(&raids)
+	if err != nil {
+		return raids, fmt.Errorf("unable to get raids: %s", err)
+	}
+
+	return raids, nil
+}
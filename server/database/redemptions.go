@@ -0,0 +1,60 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Redemption is a channel points pub sub message from twitch.
+type Redemption struct {
+	ID           bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	MessageID    string        `bson:"message_id,omitempty" json:"message_id,omitempty"`
+	RedemptionID string        `bson:"redemption_id" json:"redemption_id"`
+	ChannelID    string        `bson:"channel_id" json:"channel_id"`
+	UserID       string        `bson:"user_id" json:"user_id"`
+	UserName     string        `bson:"user_name" json:"user_name"`
+	DisplayName  string        `bson:"display_name" json:"display_name"`
+	Time         time.Time     `bson:"timestamp" json:"timestamp"`
+	RewardID     string        `bson:"reward_id" json:"reward_id"`
+	RewardTitle  string        `bson:"reward_title" json:"reward_title"`
+	RewardCost   int           `bson:"reward_cost" json:"reward_cost"`
+	UserInput    string        `bson:"user_input" json:"user_input"`
+	Status       string        `bson:"status" json:"status"`
+}
+
+// AddRedemption adds a redemption event to the database.
+func (db *Database) AddRedemption(r *Redemption) error {
+	// insert new redemption event
+	return insertEvent(db.redemptions, r)
+}
+
+// GetRedemptions returns a slice of redemption events.
+func (db *Database) GetRedemptions(channelID string, latest int64, limit int, offset int) ([]*Redemption, error) {
+	redemptions := make([]*Redemption, 0)
+
+	// convert latest to time
+	ltUnix := latest / (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	ltNano := latest % (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	lt := time.Unix(ltUnix, ltNano)
+
+	// build query
+	query := db.redemptions.Find(bson.M{
+		"channel_id": channelID,
+		"timestamp": bson.M{
+			"$gt": lt,
+		},
+	})
+
+	// add filters
+	query.Limit(limit).Skip(offset).Sort("-timestamp")
+
+	// get redemption events
+	err := query.All(&redemptions)
+	if err != nil {
+		return redemptions, fmt.Errorf("unable to get redemptions: %s", err)
+	}
+
+	return redemptions, nil
+}
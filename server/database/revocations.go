@@ -0,0 +1,22 @@
+package database
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Revocation is an eventsub subscription revoked by twitch.
+type Revocation struct {
+	ID             bson.ObjectId     `bson:"_id,omitempty" json:"ID,omitempty"`
+	SubscriptionID string            `bson:"subscription_id" json:"subscription_id"`
+	Type           string            `bson:"type" json:"type"`
+	Status         string            `bson:"status" json:"status"`
+	Condition      map[string]string `bson:"condition" json:"condition"`
+	Timestamp      time.Time         `bson:"timestamp" json:"timestamp"`
+}
+
+// AddRevocation adds an eventsub revocation to the database.
+func (db *Database) AddRevocation(r *Revocation) error {
+	return db.revocations.Insert(r)
+}
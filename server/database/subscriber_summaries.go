@@ -0,0 +1,138 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// SubscriberSummary is the subscription history for a single subscriber.
+type SubscriberSummary struct {
+	ID           bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	ChannelID    string        `bson:"channel_id" json:"channelID"`
+	SubscriberID string        `bson:"subscriber_id" json:"subscriberID"`
+	DisplayName  string        `bson:"display_name" json:"display_name"`
+
+	CumulativeMonths int       `bson:"cumulative_months" json:"cumulative_months"`
+	StreakMonths     int       `bson:"streak_months" json:"streak_months"`
+	SubPlan          string    `bson:"sub_plan" json:"sub_plan"`
+	SubPlanName      string    `bson:"sub_plan_name" json:"sub_plan_name"`
+	Events           int       `bson:"events" json:"events"`
+	FirstSubscribed  time.Time `bson:"first_subscribed" json:"first_subscribed"`
+	Timestamp        time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+// update the summary for the subscriber of a subscription event in a
+// single upsert, so events saved at the same time are all counted.
+// Twitch sends cumulative months, otherwise the event is counted, and
+// the streak is only known when the user shares it.
+func (db *Database) updateSubscriberSummary(s *Subscriber) error {
+	selector := bson.M{
+		"channel_id":    s.ChannelID,
+		"subscriber_id": s.SubscriberID,
+	}
+
+	inc := bson.M{
+		"events": 1,
+	}
+	max := bson.M{
+		"timestamp":     s.Timestamp,
+		"streak_months": 1,
+	}
+
+	if s.Months > 0 {
+		max["cumulative_months"] = s.Months
+	} else {
+		inc["cumulative_months"] = 1
+	}
+
+	set := bson.M{
+		"display_name":  s.DisplayName,
+		"sub_plan":      s.SubPlan,
+		"sub_plan_name": s.SubPlanName,
+	}
+	if s.Streak > 0 {
+		set["streak_months"] = s.Streak
+		delete(max, "streak_months")
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$inc": inc,
+		"$max": max,
+		"$min": bson.M{
+			"first_subscribed": s.Timestamp,
+		},
+	}
+
+	// save the summary, updating it if another event created it first
+	_, err := db.subscriberSummaries.Upsert(selector, update)
+	if mgo.IsDup(err) {
+		_, err = db.subscriberSummaries.Upsert(selector, update)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to save subscriber summary: %s", err)
+	}
+
+	return nil
+}
+
+// RebuildSubscriberSummaries builds the subscriber summaries again from
+// every saved subscription event, returning how many were counted.
+func (db *Database) RebuildSubscriberSummaries() (int, error) {
+	// start over
+	if _, err := db.subscriberSummaries.RemoveAll(bson.M{}); err != nil {
+		return 0, fmt.Errorf("unable to remove subscriber summaries: %s", err)
+	}
+
+	// count the events in the order they happened
+	iter := db.subscribers.Find(nil).Sort("timestamp", "_id").Iter()
+
+	counted := 0
+	var subscriber Subscriber
+	for iter.Next(&subscriber) {
+		if err := db.updateSubscriberSummary(&subscriber); err != nil {
+			iter.Close()
+			return counted, err
+		}
+		counted++
+		subscriber = Subscriber{}
+	}
+
+	if err := iter.Close(); err != nil {
+		return counted, fmt.Errorf("unable to get subscribers: %s", err)
+	}
+
+	return counted, nil
+}
+
+// GetSubscriberSummaries returns a slice of subscriber summaries.
+func (db *Database) GetSubscriberSummaries(channelID string, latest int64, limit int, offset int) ([]*SubscriberSummary, error) {
+	summaries := make([]*SubscriberSummary, 0)
+
+	// convert latest to time
+	ltUnix := latest / (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	ltNano := latest % (int64(time.Millisecond) * int64(time.Nanosecond) * 1000)
+	lt := time.Unix(ltUnix, ltNano)
+
+	// build query
+	query := db.subscriberSummaries.Find(bson.M{
+		"channel_id": channelID,
+		"timestamp": bson.M{
+			"$gt": lt,
+		},
+	})
+
+	// add filters
+	query.Limit(limit).Skip(offset).Sort("-timestamp")
+
+	// get subscriber summaries
+	err := query.All(&summaries)
+	if err != nil {
+		return summaries, fmt.Errorf("unable to get subscriber summaries: %s", err)
+	}
+
+	return summaries, nil
+}
@@ -2,14 +2,17 @@ package database
 
 import (
 	"fmt"
+	"log"
 	"time"
 
+	mgo "gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
 // Subscriber is a twitch subscriber.
 type Subscriber struct {
 	ID           bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	MessageID    string        `bson:"message_id,omitempty" json:"message_id,omitempty"`
 	ChannelID    string        `bson:"channel_id,omitempty" json:"channelID,omitempty"`
 	SubscriberID string        `bson:"subscriber_id,omitempty" json:"subscriberID,omitempty"`
 	Timestamp    time.Time     `bson:"timestamp,omitempty" json:"timestamp,omitempty"`
@@ -18,8 +21,27 @@ type Subscriber struct {
 	SubPlan     string      `bson:"sub_plan" json:"sub_plan"`
 	SubPlanName string      `bson:"sub_plan_name" json:"sub_plan_name"`
 	Months      int         `bson:"months" json:"months"`
+	Streak      int         `bson:"streak_months" json:"streak_months"`
 	Context     string      `bson:"context" json:"context"`
 	SubMessage  *SubMessage `bson:"sub_message" json:"sub_message"`
+
+	IsGift             bool   `bson:"is_gift" json:"is_gift"`
+	RecipientID        string `bson:"recipient_id,omitempty" json:"recipient_id,omitempty"`
+	RecipientName      string `bson:"recipient_name,omitempty" json:"recipient_name,omitempty"`
+	GifterID           string `bson:"gifter_id,omitempty" json:"gifter_id,omitempty"`
+	GifterName         string `bson:"gifter_name,omitempty" json:"gifter_name,omitempty"`
+	IsAnonymous        bool   `bson:"is_anonymous" json:"is_anonymous"`
+	MultiMonthDuration int    `bson:"multi_month_duration" json:"multi_month_duration"`
+	GiftID             string `bson:"gift_id,omitempty" json:"gift_id,omitempty"`
+
+	// set when recovered from helix after pub sub was down
+	Backfilled bool `bson:"backfilled,omitempty" json:"backfilled,omitempty"`
+	// start of the gap a backfilled event happened in, it's
+	// timestamped when it was recovered
+	BackfilledFrom time.Time `bson:"backfilled_from,omitempty" json:"backfilled_from,omitempty"`
+
+	// embedded from the user profile cache
+	ProfileImageURL string `bson:"-" json:"profile_image_url,omitempty"`
 }
 
 // SubMessage is the message sent when a user subscribes.
@@ -36,49 +58,68 @@ type SubMessageEmote struct {
 	ID    int `bson:"id" json:"id"`
 }
 
-// AddSubscriber adds a subscriber to the database.
+// AddSubscriber adds a subscription event to the database and updates
+// the subscriber summary. Every event is kept so resubs show up too.
+// Duplicates are caught by the insert, before they're counted in a
+// gift or summary.
 func (db *Database) AddSubscriber(s *Subscriber) error {
-	// check if subscriber already exists
-	subscribed, err := db.hasSubscriber(s.ChannelID, s.SubscriberID)
-	if err != nil {
+	if len(s.ID) == 0 {
+		s.ID = bson.NewObjectId()
+	}
+
+	// insert new subscription event
+	if err := insertEvent(db.subscribers, s); err != nil {
 		return err
 	}
 
-	// TODO: figure out how we're going to handle duplicate subs
-	// skip adding subscriber to database if they are already subscribed
-	if subscribed {
-		return fmt.Errorf("found duplicate subscriber [%s] for channel [%s]", s.SubscriberID, s.ChannelID)
+	// bundle gifted subs in to their gift event, the sub is kept
+	// without its gift if that fails
+	if s.IsGift {
+		if err := db.bundleGift(s); err != nil {
+			log.Printf("[ERROR] database: subscriber [%s]: %s", s.ID.Hex(), err)
+		}
 	}
 
-	// insert new subscriber
-	return db.subscribers.Insert(s)
+	// update the subscriber summary
+	return db.updateSubscriberSummary(s)
 }
 
-// check for subscriber
-func (db *Database) hasSubscriber(channelID string, subscriberID string) (bool, error) {
-	// build query
-	query := db.subscribers.Find(bson.M{
-		"channel_id":    channelID,
-		"subscriber_id": subscriberID,
-	})
+// RemoveSubscriber removes every subscription event of a subscriber
+// from the database, along with their summary.
+func (db *Database) RemoveSubscriber(s *Subscriber) error {
+	selector := bson.M{
+		"channel_id":    s.ChannelID,
+		"subscriber_id": s.SubscriberID,
+	}
 
-	// get count
-	count, err := query.Count()
-	if err != nil {
-		return false, err
+	// remove the subscription events
+	if _, err := db.subscribers.RemoveAll(selector); err != nil {
+		return fmt.Errorf("unable to remove subscriber: %s", err)
 	}
 
-	// return if we found a subscriber
-	return count > 0, nil
+	// the summary only counted their events
+	if err := db.subscriberSummaries.Remove(selector); err != nil && err != mgo.ErrNotFound {
+		return fmt.Errorf("unable to remove subscriber summary: %s", err)
+	}
+
+	return nil
 }
 
-// RemoveSubscriber removes a subscriber from the database.
-func (db *Database) RemoveSubscriber(s *Subscriber) error {
-	// remove the subscriber from the database
-	return db.subscribers.Remove(bson.M{
-		"channel_id":    s.ChannelID,
-		"subscriber_id": s.SubscriberID,
-	})
+// HasSubscriberSince checks if a subscription event for the subscriber
+// was saved since the given time.
+func (db *Database) HasSubscriberSince(channelID string, subscriberID string, since time.Time) (bool, error) {
+	n, err := db.subscribers.Find(bson.M{
+		"channel_id":    channelID,
+		"subscriber_id": subscriberID,
+		"timestamp": bson.M{
+			"$gte": since,
+		},
+	}).Count()
+	if err != nil {
+		return false, fmt.Errorf("unable to find subscriber: %s", err)
+	}
+
+	return n > 0, nil
 }
 
 // GetSubscribers returns a slice of subscribers.
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// User is a cached twitch user profile.
+type User struct {
+	ID              string    `bson:"_id" json:"id"`
+	Login           string    `bson:"login" json:"login"`
+	DisplayName     string    `bson:"display_name" json:"display_name"`
+	ProfileImageURL string    `bson:"profile_image_url" json:"profile_image_url"`
+	UpdatedAt       time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// SaveUser adds or updates a user profile.
+func (db *Database) SaveUser(u *User) error {
+	if _, err := db.users.UpsertId(u.ID, u); err != nil {
+		return fmt.Errorf("unable to save user: %s", err)
+	}
+
+	return nil
+}
+
+// GetUsers returns the cached profiles for the user ids, keyed by id.
+func (db *Database) GetUsers(ids []string) (map[string]*User, error) {
+	users := make(map[string]*User)
+
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	// get users
+	var found []*User
+	err := db.users.Find(bson.M{
+		"_id": bson.M{
+			"$in": ids,
+		},
+	}).All(&found)
+	if err != nil {
+		return users, fmt.Errorf("unable to get users: %s", err)
+	}
+
+	for _, u := range found {
+		users[u.ID] = u
+	}
+
+	return users, nil
+}
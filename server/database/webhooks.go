@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	mgo "gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Webhook is an active twitch webhook subscription.
+type Webhook struct {
+	ID           bson.ObjectId `bson:"_id,omitempty" json:"ID,omitempty"`
+	ChannelID    string        `bson:"channel_id" json:"channelID"`
+	Topic        string        `bson:"topic" json:"topic"`
+	Secret       string        `bson:"secret" json:"-"`
+	LeaseSeconds int           `bson:"lease_seconds" json:"lease_seconds"`
+	Verified     bool          `bson:"verified" json:"verified"`
+	ExpiresAt    time.Time     `bson:"expires_at" json:"expires_at"`
+	Timestamp    time.Time     `bson:"timestamp" json:"timestamp"`
+}
+
+// SaveWebhook adds or replaces the webhook subscription for its topic.
+func (db *Database) SaveWebhook(wh *Webhook) error {
+	if _, err := db.webhooks.Upsert(bson.M{"topic": wh.Topic}, bson.M{
+		"$set": bson.M{
+			"channel_id":    wh.ChannelID,
+			"topic":         wh.Topic,
+			"secret":        wh.Secret,
+			"lease_seconds": wh.LeaseSeconds,
+			"verified":      wh.Verified,
+			"expires_at":    wh.ExpiresAt,
+			"timestamp":     wh.Timestamp,
+		},
+	}); err != nil {
+		return fmt.Errorf("unable to save webhook: %s", err)
+	}
+
+	return nil
+}
+
+// GetWebhook returns the webhook subscription for a topic.
+func (db *Database) GetWebhook(topic string) (*Webhook, error) {
+	var wh Webhook
+	err := db.webhooks.Find(bson.M{"topic": topic}).One(&wh)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get webhook: %s", err)
+	}
+
+	return &wh, nil
+}
+
+// GetWebhooks returns every webhook subscription.
+func (db *Database) GetWebhooks() ([]*Webhook, error) {
+	webhooks := make([]*Webhook, 0)
+
+	if err := db.webhooks.Find(nil).Sort("expires_at").All(&webhooks); err != nil {
+		return webhooks, fmt.Errorf("unable to get webhooks: %s", err)
+	}
+
+	return webhooks, nil
+}
+
+// VerifyWebhook marks a webhook subscription as verified with
+// the lease twitch granted.
+func (db *Database) VerifyWebhook(topic string, leaseSeconds int) error {
+	err := db.webhooks.Update(bson.M{"topic": topic}, bson.M{
+		"$set": bson.M{
+			"lease_seconds": leaseSeconds,
+			"verified":      true,
+			"expires_at":    time.Now().Add(time.Duration(leaseSeconds) * time.Second),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to verify webhook: %s", err)
+	}
+
+	return nil
+}
+
+// RemoveWebhook removes the webhook subscription for a topic.
+func (db *Database) RemoveWebhook(topic string) error {
+	err := db.webhooks.Remove(bson.M{"topic": topic})
+	if err != nil && err != mgo.ErrNotFound {
+		return fmt.Errorf("unable to remove webhook: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/codephobia/twitch-eos-thanks/server/metrics"
+)
+
+// metrics counted by the bus
+const (
+	// MetricDropped prefixes the counters of events a queued sink had
+	// no room for, e.g. events.dropped.webhooks.
+	MetricDropped = "events.dropped."
+	// MetricSinkErrors prefixes the counters of events a queued sink
+	// failed to handle, e.g. events.errors.webhooks.
+	MetricSinkErrors = "events.errors."
+)
+
+var (
+	// ErrBusClosed is returned when publishing to a closed bus.
+	ErrBusClosed = fmt.Errorf("event bus closed")
+)
+
+// Sink handles the events published to a bus.
+type Sink interface {
+	HandleEvent(e Event) error
+}
+
+// Bus hands the events published by ingestion to every subscribed sink.
+// Inline sinks handle an event before publishing returns, in the order
+// they subscribed, and the first to fail stops the event. Queued sinks
+// only see events every inline sink handled, each on its own goroutine
+// with its own buffer, so a slow or failing one holds up nothing else.
+type Bus struct {
+	metrics *metrics.Metrics
+
+	mu     sync.RWMutex
+	inline []*subscription
+	queued []*subscription
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// subscription is a sink subscribed to the bus.
+type subscription struct {
+	name  string
+	sink  Sink
+	queue chan Event
+}
+
+// NewBus returns a new bus with no sinks.
+func NewBus(m *metrics.Metrics) *Bus {
+	return &Bus{
+		metrics: m,
+	}
+}
+
+// Subscribe adds a sink to the bus. A sink with no buffer is handled
+// inline and its errors are returned from Publish, otherwise events are
+// queued for it, dropping any it has no room for.
+func (b *Bus) Subscribe(name string, sink Sink, buffer int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := &subscription{
+		name: name,
+		sink: sink,
+	}
+
+	if buffer <= 0 {
+		b.inline = append(b.inline, s)
+		return
+	}
+
+	s.queue = make(chan Event, buffer)
+	b.queued = append(b.queued, s)
+
+	b.wg.Add(1)
+	go b.run(s)
+}
+
+// Publish hands an event to every sink, returning the error of the
+// inline sink that failed to handle it.
+func (b *Bus) Publish(e Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrBusClosed
+	}
+
+	for _, s := range b.inline {
+		if err := s.sink.HandleEvent(e); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range b.queued {
+		select {
+		case s.queue <- e:
+		default:
+			log.Printf("[ERROR] events: %s: queue full, dropping %s event", s.name, e.Kind())
+			b.metrics.Inc(MetricDropped + s.name)
+		}
+	}
+
+	return nil
+}
+
+// Closer is a sink with work of its own to stop once the bus is closed.
+type Closer interface {
+	Close()
+}
+
+// Close stops the bus, waiting for the queued sinks to handle
+// every event already published, then closes the sinks that are
+// Closers.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+
+	for _, s := range b.queued {
+		close(s.queue)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+
+	for _, s := range append(b.inline, b.queued...) {
+		if closer, ok := s.sink.(Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// handles the events queued for a sink until the bus is closed
+func (b *Bus) run(s *subscription) {
+	defer b.wg.Done()
+
+	for e := range s.queue {
+		if err := s.sink.HandleEvent(e); err != nil {
+			log.Printf("[ERROR] events: %s: %s event: %s", s.name, e.Kind(), err)
+			b.metrics.Inc(MetricSinkErrors + s.name)
+		}
+	}
+}
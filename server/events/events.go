@@ -0,0 +1,189 @@
+package events
+
+import (
+	"time"
+)
+
+// Kind is the kind of an event.
+type Kind string
+
+// kinds of events
+const (
+	KindFollow       Kind = "follow"
+	KindSubscription Kind = "subscription"
+	KindGift         Kind = "gift"
+	KindCheer        Kind = "cheer"
+	KindPurchase     Kind = "purchase"
+	KindRedemption   Kind = "redemption"
+	KindRaid         Kind = "raid"
+)
+
+// sources events are ingested from
+const (
+	SourcePubSub    = "pubsub"
+	SourceEventSub  = "eventsub"
+	SourceWebhook   = "webhook"
+	SourceBackfill  = "backfill"
+	SourceReconcile = "reconcile"
+)
+
+// Event is a twitch event, the same whichever source it came from.
+type Event interface {
+	Kind() Kind
+	Metadata() *Meta
+}
+
+// Meta is what every event carries.
+type Meta struct {
+	// id twitch sent the event with, used to drop duplicates
+	MessageID string    `json:"message_id,omitempty"`
+	Source    string    `json:"source"`
+	ChannelID string    `json:"channel_id"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// set when recovered from helix after pub sub was down
+	Backfilled bool `json:"backfilled,omitempty"`
+	// start of the gap a backfilled event happened in, it's
+	// timestamped when it was recovered
+	BackfilledFrom time.Time `json:"backfilled_from,omitempty"`
+}
+
+// Metadata returns the meta of the event.
+func (m *Meta) Metadata() *Meta {
+	return m
+}
+
+// Message is a chat message sent with an event.
+type Message struct {
+	Text   string   `json:"text"`
+	Emotes []*Emote `json:"emotes"`
+}
+
+// Emote is a twitch emote contained within a message.
+type Emote struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+	ID    int `json:"id"`
+}
+
+// Follow is a user following a channel.
+type Follow struct {
+	Meta
+	UserID string `json:"user_id"`
+}
+
+// Kind returns KindFollow.
+func (f *Follow) Kind() Kind {
+	return KindFollow
+}
+
+// Subscription is a user subscribing or resubscribing, or being gifted
+// a sub. Gifted subs belong to the recipient, crediting the gifter.
+type Subscription struct {
+	Meta
+	UserID             string   `json:"user_id"`
+	UserName           string   `json:"user_name"`
+	Tier               string   `json:"tier"`
+	PlanName           string   `json:"plan_name"`
+	Months             int      `json:"months"`
+	Streak             int      `json:"streak_months"`
+	MultiMonthDuration int      `json:"multi_month_duration"`
+	Context            string   `json:"context"`
+	Message            *Message `json:"message"`
+
+	IsGift      bool   `json:"is_gift"`
+	IsAnonymous bool   `json:"is_anonymous"`
+	GifterID    string `json:"gifter_id,omitempty"`
+	GifterName  string `json:"gifter_name,omitempty"`
+}
+
+// Kind returns KindSubscription.
+func (s *Subscription) Kind() Kind {
+	return KindSubscription
+}
+
+// Gift is a gifter giving away subs to the community.
+type Gift struct {
+	Meta
+	GifterID    string `json:"gifter_id"`
+	GifterName  string `json:"gifter_name"`
+	IsAnonymous bool   `json:"is_anonymous"`
+	Tier        string `json:"tier"`
+	Count       int    `json:"count"`
+}
+
+// Kind returns KindGift.
+func (g *Gift) Kind() Kind {
+	return KindGift
+}
+
+// Cheer is a user cheering bits.
+type Cheer struct {
+	Meta
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	ChannelName string `json:"channel_name"`
+	Message     string `json:"message"`
+	Bits        int    `json:"bits"`
+	TotalBits   int    `json:"total_bits"`
+	Context     string `json:"context"`
+
+	// badge versions, set when the cheer earned a new badge
+	BadgeVersion         int `json:"badge_version"`
+	PreviousBadgeVersion int `json:"previous_badge_version"`
+}
+
+// Kind returns KindCheer.
+func (c *Cheer) Kind() Kind {
+	return KindCheer
+}
+
+// Purchase is a user buying something that supports the channel.
+type Purchase struct {
+	Meta
+	UserID          string   `json:"user_id"`
+	UserName        string   `json:"user_name"`
+	DisplayName     string   `json:"display_name"`
+	ChannelName     string   `json:"channel_name"`
+	ItemImageURL    string   `json:"item_image_url"`
+	ItemDescription string   `json:"item_description"`
+	SupportsChannel bool     `json:"supports_channel"`
+	Message         *Message `json:"message"`
+}
+
+// Kind returns KindPurchase.
+func (p *Purchase) Kind() Kind {
+	return KindPurchase
+}
+
+// Redemption is a user redeeming a channel points reward.
+type Redemption struct {
+	Meta
+	RedemptionID string `json:"redemption_id"`
+	UserID       string `json:"user_id"`
+	UserName     string `json:"user_name"`
+	DisplayName  string `json:"display_name"`
+	RewardID     string `json:"reward_id"`
+	RewardTitle  string `json:"reward_title"`
+	RewardCost   int    `json:"reward_cost"`
+	UserInput    string `json:"user_input"`
+	Status       string `json:"status"`
+}
+
+// Kind returns KindRedemption.
+func (r *Redemption) Kind() Kind {
+	return KindRedemption
+}
+
+// Raid is a broadcaster raiding a channel.
+type Raid struct {
+	Meta
+	RaiderID   string `json:"raider_id"`
+	RaiderName string `json:"raider_name"`
+	Viewers    int    `json:"viewers"`
+}
+
+// Kind returns KindRaid.
+func (r *Raid) Kind() Kind {
+	return KindRaid
+}
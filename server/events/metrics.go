@@ -0,0 +1,27 @@
+package events
+
+import (
+	"github.com/codephobia/twitch-eos-thanks/server/metrics"
+)
+
+// MetricEvents prefixes the counters of events handled by the bus by
+// kind, e.g. events.cheer.
+const MetricEvents = "events."
+
+// MetricsSink counts events by kind.
+type MetricsSink struct {
+	metrics *metrics.Metrics
+}
+
+// NewMetricsSink returns a new metrics sink.
+func NewMetricsSink(m *metrics.Metrics) *MetricsSink {
+	return &MetricsSink{
+		metrics: m,
+	}
+}
+
+// HandleEvent counts an event.
+func (s *MetricsSink) HandleEvent(e Event) error {
+	s.metrics.Inc(MetricEvents + string(e.Kind()))
+	return nil
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+// StorageSink saves events to the database. It's subscribed inline so
+// ingestion sees what failed to save, including database.ErrDuplicate
+// for events already saved.
+type StorageSink struct {
+	database *database.Database
+}
+
+// NewStorageSink returns a new storage sink.
+func NewStorageSink(db *database.Database) *StorageSink {
+	return &StorageSink{
+		database: db,
+	}
+}
+
+// HandleEvent saves an event.
+func (s *StorageSink) HandleEvent(e Event) error {
+	switch e := e.(type) {
+	case *Follow:
+		return s.database.AddFollower(&database.Follower{
+			MessageID:      e.MessageID,
+			ChannelID:      e.ChannelID,
+			FollowerID:     e.UserID,
+			Timestamp:      e.Timestamp,
+			Backfilled:     e.Backfilled,
+			BackfilledFrom: e.BackfilledFrom,
+		})
+	case *Subscription:
+		subscriber := &database.Subscriber{
+			MessageID:    e.MessageID,
+			ChannelID:    e.ChannelID,
+			SubscriberID: e.UserID,
+			Timestamp:    e.Timestamp,
+
+			DisplayName: e.UserName,
+			SubPlan:     e.Tier,
+			SubPlanName: e.PlanName,
+			Months:      e.Months,
+			Streak:      e.Streak,
+			Context:     e.Context,
+			SubMessage:  &database.SubMessage{},
+
+			MultiMonthDuration: e.MultiMonthDuration,
+
+			Backfilled:     e.Backfilled,
+			BackfilledFrom: e.BackfilledFrom,
+		}
+
+		if e.Message != nil {
+			subscriber.SubMessage.Message = e.Message.Text
+			subscriber.SubMessage.Emotes = storageEmotes(e.Message.Emotes)
+		}
+
+		// gifted subs are bundled in to their gift event
+		if e.IsGift {
+			subscriber.IsGift = true
+			subscriber.IsAnonymous = e.IsAnonymous
+			subscriber.GifterID = e.GifterID
+			subscriber.GifterName = e.GifterName
+			subscriber.RecipientID = e.UserID
+			subscriber.RecipientName = e.UserName
+		}
+
+		return s.database.AddSubscriber(subscriber)
+	case *Gift:
+		return s.database.AddGift(&database.Gift{
+			MessageID:   e.MessageID,
+			ChannelID:   e.ChannelID,
+			GifterID:    e.GifterID,
+			GifterName:  e.GifterName,
+			IsAnonymous: e.IsAnonymous,
+			SubPlan:     e.Tier,
+			Count:       e.Count,
+			Timestamp:   e.Timestamp,
+		})
+	case *Cheer:
+		return s.database.AddBit(&database.Bit{
+			MessageID:     e.MessageID,
+			UserName:      e.UserName,
+			ChannelName:   e.ChannelName,
+			UserID:        e.UserID,
+			ChannelID:     e.ChannelID,
+			Time:          e.Timestamp,
+			ChatMessage:   e.Message,
+			BitsUsed:      e.Bits,
+			TotalBitsUsed: e.TotalBits,
+			Context:       e.Context,
+			BadgeEntitlement: &database.BadgeEntitlement{
+				NewVersion:      e.BadgeVersion,
+				PreviousVersion: e.PreviousBadgeVersion,
+			},
+			Backfilled:     e.Backfilled,
+			BackfilledFrom: e.BackfilledFrom,
+		})
+	case *Purchase:
+		purchaseMessage := &database.PurchaseMessage{}
+		if e.Message != nil {
+			purchaseMessage.Message = e.Message.Text
+			purchaseMessage.Emotes = storageEmotes(e.Message.Emotes)
+		}
+
+		return s.database.AddCommerce(&database.Commerce{
+			MessageID:       e.MessageID,
+			UserName:        e.UserName,
+			DisplayName:     e.DisplayName,
+			ChannelName:     e.ChannelName,
+			UserID:          e.UserID,
+			ChannelID:       e.ChannelID,
+			Time:            e.Timestamp,
+			ItemImageURL:    e.ItemImageURL,
+			ItemDescription: e.ItemDescription,
+			SupportsChannel: e.SupportsChannel,
+			PurchaseMessage: purchaseMessage,
+		})
+	case *Redemption:
+		return s.database.AddRedemption(&database.Redemption{
+			MessageID:    e.MessageID,
+			RedemptionID: e.RedemptionID,
+			ChannelID:    e.ChannelID,
+			UserID:       e.UserID,
+			UserName:     e.UserName,
+			DisplayName:  e.DisplayName,
+			Time:         e.Timestamp,
+			RewardID:     e.RewardID,
+			RewardTitle:  e.RewardTitle,
+			RewardCost:   e.RewardCost,
+			UserInput:    e.UserInput,
+			Status:       e.Status,
+		})
+	case *Raid:
+		return s.database.AddRaid(&database.Raid{
+			MessageID:  e.MessageID,
+			ChannelID:  e.ChannelID,
+			RaiderID:   e.RaiderID,
+			RaiderName: e.RaiderName,
+			Viewers:    e.Viewers,
+			Timestamp:  e.Timestamp,
+		})
+	}
+
+	return fmt.Errorf("unknown event kind: %s", e.Kind())
+}
+
+// converts message emotes to their database form
+func storageEmotes(emotes []*Emote) []*database.SubMessageEmote {
+	messageEmotes := make([]*database.SubMessageEmote, 0, len(emotes))
+	for _, emote := range emotes {
+		messageEmotes = append(messageEmotes, &database.SubMessageEmote{
+			Start: emote.Start,
+			End:   emote.End,
+			ID:    emote.ID,
+		})
+	}
+
+	return messageEmotes
+}
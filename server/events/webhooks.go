@@ -0,0 +1,138 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/backoff"
+)
+
+var (
+	webhookTimeout  = 10 * time.Second
+	webhookAttempts = 3
+	// the most deliveries in flight at once, across every url
+	webhookDeliveries = 64
+)
+
+// WebhookSink posts events to webhook urls. Each url is delivered to on
+// its own goroutine, retried with a backoff before giving up on it, so
+// a slow or failing url holds up nothing else.
+type WebhookSink struct {
+	urls   []string
+	client *http.Client
+
+	// holds a slot per delivery in flight
+	deliveries chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	wg         sync.WaitGroup
+}
+
+// WebhookPayload is the body posted to a webhook.
+type WebhookPayload struct {
+	Kind  Kind  `json:"kind"`
+	Event Event `json:"event"`
+}
+
+// NewWebhookSink returns a new webhook sink posting to the urls.
+func NewWebhookSink(urls []string) *WebhookSink {
+	return &WebhookSink{
+		urls: urls,
+		client: &http.Client{
+			Timeout: webhookTimeout,
+		},
+
+		deliveries: make(chan struct{}, webhookDeliveries),
+		done:       make(chan struct{}),
+	}
+}
+
+// HandleEvent starts delivering an event to every webhook url, returning
+// an error if any of them had to be dropped with too many deliveries in
+// flight. Failed deliveries are logged once they're out of attempts.
+func (s *WebhookSink) HandleEvent(e Event) error {
+	body, err := json.Marshal(&WebhookPayload{
+		Kind:  e.Kind(),
+		Event: e,
+	})
+	if err != nil {
+		return fmt.Errorf("encode: %s", err)
+	}
+
+	var lastErr error
+	for _, url := range s.urls {
+		select {
+		case s.deliveries <- struct{}{}:
+		default:
+			lastErr = fmt.Errorf("%s: too many deliveries in flight, dropping", url)
+			continue
+		}
+
+		s.wg.Add(1)
+		go s.deliver(url, body)
+	}
+
+	return lastErr
+}
+
+// Close stops retrying deliveries, waiting for those in flight.
+func (s *WebhookSink) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+
+	s.wg.Wait()
+}
+
+// delivers a body to a webhook url, freeing its slot once done
+func (s *WebhookSink) deliver(url string, body []byte) {
+	defer s.wg.Done()
+	defer func() {
+		<-s.deliveries
+	}()
+
+	if err := s.post(url, body); err != nil {
+		log.Printf("[ERROR] events: webhooks: %s: %s", url, err)
+	}
+}
+
+// posts a body to a webhook url until it's taken, out of attempts or
+// the sink is closed
+func (s *WebhookSink) post(url string, body []byte) error {
+	b := &backoff.Backoff{
+		Min:    1 * time.Second,
+		Max:    10 * time.Second,
+		Factor: 2,
+		Jitter: true,
+	}
+
+	var err error
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(b.Duration()):
+			case <-s.done:
+				return fmt.Errorf("closed before retrying: %s", err)
+			}
+		}
+
+		var resp *http.Response
+		resp, err = s.client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("status: %d", resp.StatusCode)
+	}
+
+	return err
+}
@@ -21,6 +21,7 @@ chmod +x ~/$folder/service.sh
 # check for config, and if not copy default
 if [ ! -f ~/$folder/config.json ]; then
     cp ./config.default.json ~/$folder/config.json
+    chmod 600 ~/$folder/config.json
 fi
 
 # complete
@@ -1,14 +1,29 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	api "github.com/codephobia/twitch-eos-thanks/server/api"
 	config "github.com/codephobia/twitch-eos-thanks/server/config"
 	database "github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+	"github.com/codephobia/twitch-eos-thanks/server/metrics"
 	"github.com/codephobia/twitch-eos-thanks/server/twitch"
 )
 
+var (
+	eventsMetricsBuffer  = 1024
+	eventsWebhooksBuffer = 256
+
+	// how long requests in flight get to finish on shutdown
+	shutdownTimeout = 5 * time.Second
+)
+
 type Main struct {
 	config   *config.Config
 	database *database.Database
@@ -16,8 +31,16 @@ type Main struct {
 }
 
 func main() {
+	// run a command instead of the server when one is given
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("[ERROR] %s: %s", os.Args[1], err)
+		}
+		return
+	}
+
 	// make a new main
-	err, _ := NewMain()
+	_, err := NewMain()
 
 	if err != nil {
 		log.Fatalf("[ERROR] main: %s", err)
@@ -37,18 +60,42 @@ func NewMain() (*Main, error) {
 		return nil, err
 	}
 
+	// event bus
+	m := metrics.NewMetrics()
+	bus := newEventBus(c, db, m)
+
 	// init twitch
-	t := twitch.NewTwitch(c, db)
+	t := twitch.NewTwitch(c, db, m, bus)
 	if err := t.Init(); err != nil {
 		return nil, err
 	}
 
 	// api
-	api := api.NewAPI(c, db)
+	api := api.NewAPI(c, db, t, bus)
+
+	// stop twitch and the api on a signal
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+
+		log.Printf("[INFO] main: shutting down")
+		t.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := api.Shutdown(ctx); err != nil {
+			log.Printf("[ERROR] main: api shutdown: %s", err)
+		}
+	}()
+
 	if err := api.Init(); err != nil {
 		return nil, err
 	}
 
+	// hand off the events still queued
+	bus.Close()
+
 	// return main
 	return &Main{
 		config:   c,
@@ -56,3 +103,19 @@ func NewMain() (*Main, error) {
 		api:      api,
 	}, nil
 }
+
+// returns the event bus ingested events are published to. Events are
+// saved before any other sink sees them, so duplicates and events that
+// failed to save go no further.
+func newEventBus(c *config.Config, db *database.Database, m *metrics.Metrics) *events.Bus {
+	bus := events.NewBus(m)
+	bus.Subscribe("storage", events.NewStorageSink(db), 0)
+	bus.Subscribe("metrics", events.NewMetricsSink(m), eventsMetricsBuffer)
+
+	// post events to any configured webhooks
+	if len(c.EventWebhooks) > 0 {
+		bus.Subscribe("webhooks", events.NewWebhookSink(c.EventWebhooks), eventsWebhooksBuffer)
+	}
+
+	return bus
+}
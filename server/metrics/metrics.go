@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// Metrics counts things happening across the server.
+type Metrics struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+// NewMetrics returns new metrics with every counter at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counters: make(map[string]int64),
+	}
+}
+
+// Inc adds one to a counter.
+func (m *Metrics) Inc(name string) {
+	m.Add(name, 1)
+}
+
+// Add adds n to a counter.
+func (m *Metrics) Add(name string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counters[name] += n
+}
+
+// Counters returns a copy of every counter.
+func (m *Metrics) Counters() map[string]int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counters := make(map[string]int64, len(m.counters))
+	for name, n := range m.counters {
+		counters[name] = n
+	}
+
+	return counters
+}
@@ -0,0 +1,267 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KDFKeyFile encrypts secrets with a random key kept in a key file.
+	KDFKeyFile = "keyfile"
+	// KDFScrypt encrypts secrets with a key derived from a passphrase.
+	KDFScrypt = "scrypt"
+
+	keySize  = 32
+	saltSize = 16
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Store keeps secrets encrypted at rest.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	kdf     string
+	key     []byte
+	salt    []byte
+	secrets map[string]string
+}
+
+// storeFile is the encrypted secret store file.
+type storeFile struct {
+	KDF   string `json:"kdf"`
+	Salt  []byte `json:"salt,omitempty"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// NewStore returns a new secret store for the given file.
+func NewStore(path string) *Store {
+	return &Store{
+		path:    path,
+		secrets: make(map[string]string),
+	}
+}
+
+// Open loads the secret store. A key is derived from the passphrase if
+// one is given, otherwise the key is read from the key file, which is
+// created if it doesn't exist yet.
+func (s *Store) Open(keyFile string, passphrase string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// read the existing store
+	var file *storeFile
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("secrets read: %s", err)
+	}
+	if err == nil {
+		file = &storeFile{}
+		if err := json.Unmarshal(data, file); err != nil {
+			return fmt.Errorf("secrets decode: %s", err)
+		}
+	}
+
+	// get the store key
+	if len(passphrase) > 0 {
+		err = s.passphraseKey(file, passphrase)
+	} else {
+		err = s.fileKey(file, keyFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	// new store
+	if file == nil {
+		return nil
+	}
+
+	// decrypt secrets
+	plaintext, err := s.decrypt(file.Nonce, file.Data)
+	if err != nil {
+		return fmt.Errorf("secrets decrypt: %s", err)
+	}
+
+	if err := json.Unmarshal(plaintext, &s.secrets); err != nil {
+		return fmt.Errorf("secrets decode: %s", err)
+	}
+
+	return nil
+}
+
+// derive the store key from a passphrase
+func (s *Store) passphraseKey(file *storeFile, passphrase string) error {
+	if file != nil && file.KDF != KDFScrypt {
+		return fmt.Errorf("secrets: store was not encrypted with a passphrase")
+	}
+
+	// use the store salt, or create one for a new store
+	if file != nil {
+		s.salt = file.Salt
+	} else {
+		s.salt = make([]byte, saltSize)
+		if _, err := rand.Read(s.salt); err != nil {
+			return fmt.Errorf("secrets salt: %s", err)
+		}
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), s.salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return fmt.Errorf("secrets key: %s", err)
+	}
+
+	s.kdf = KDFScrypt
+	s.key = key
+
+	return nil
+}
+
+// read the store key from a key file
+func (s *Store) fileKey(file *storeFile, keyFile string) error {
+	if file != nil && file.KDF != KDFKeyFile {
+		return fmt.Errorf("secrets: store was not encrypted with a key file")
+	}
+
+	data, err := ioutil.ReadFile(keyFile)
+
+	// create a key for a new store
+	if os.IsNotExist(err) && file == nil {
+		key := make([]byte, keySize)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("secrets key: %s", err)
+		}
+
+		if err := WriteFile(keyFile, []byte(hex.EncodeToString(key)), 0600); err != nil {
+			return fmt.Errorf("secrets key write: %s", err)
+		}
+
+		s.kdf = KDFKeyFile
+		s.key = key
+
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("secrets key read: %s", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return fmt.Errorf("secrets key: invalid key file")
+	}
+
+	s.kdf = KDFKeyFile
+	s.key = key
+
+	return nil
+}
+
+// Get returns a secret.
+func (s *Store) Get(name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.secrets[name]
+	return value, ok
+}
+
+// Set sets a secret, removing it if the value is empty.
+func (s *Store) Set(name string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(value) == 0 {
+		delete(s.secrets, name)
+		return
+	}
+
+	s.secrets[name] = value
+}
+
+// Save encrypts the secrets and writes them to the store file.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.key == nil {
+		return fmt.Errorf("secrets: store not open")
+	}
+
+	// marshal secrets
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	// encrypt secrets
+	nonce, data, err := s.encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("secrets encrypt: %s", err)
+	}
+
+	// marshal store file
+	fileJSON, err := json.MarshalIndent(&storeFile{
+		KDF:   s.kdf,
+		Salt:  s.salt,
+		Nonce: nonce,
+		Data:  data,
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFile(s.path, fileJSON, 0600)
+}
+
+// encrypt plaintext with the store key
+func (s *Store) encrypt(plaintext []byte) ([]byte, []byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// decrypt data with the store key
+func (s *Store) decrypt(nonce []byte, data []byte) ([]byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+
+	return gcm.Open(nil, nonce, data, nil)
+}
+
+// returns an aes-gcm cipher for the store key
+func (s *Store) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
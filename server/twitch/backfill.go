@@ -0,0 +1,710 @@
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/helix"
+	"github.com/codephobia/twitch-eos-thanks/server/config"
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+)
+
+var (
+	TWITCH_HELIX_SUBSCRIPTIONS_URL    string = "/subscriptions?first=100&broadcaster_id="
+	TWITCH_HELIX_BITS_LEADERBOARD_URL string = "/bits/leaderboard?period=all&count="
+
+	// the most cheerers the bits leaderboard returns
+	helixLeaderboardMax = 100
+
+	backfillTimeout = 5 * time.Minute
+)
+
+// MetricBackfilled prefixes the counters of events backfilled after
+// pub sub gaps, e.g. backfilled.bits.
+const MetricBackfilled = "backfilled."
+
+// Backfill recovers events sent while a channel wasn't listened to on
+// pub sub. Follows are paged from helix back to the start of the gap.
+// Helix has no history of subs or cheers, so they're found by diffing
+// the subscriptions and bits leaderboard against a baseline taken while
+// the channel was listened to, leaving out anything pub sub delivered.
+// Baselines are saved to the database with when the channel was last
+// listened to, so the time the server was down is backfilled too.
+type Backfill struct {
+	config   *config.Config
+	database *database.Database
+	twitch   *Twitch
+
+	// one backfill or baseline at a time
+	run sync.Mutex
+
+	mu        sync.Mutex
+	baselines map[string]*backfillBaseline
+	clients   map[string]*helix.Client
+}
+
+// backfillBaseline is what helix reported for a channel while it was
+// listened to. Either part is nil if it couldn't be read.
+type backfillBaseline struct {
+	subs *backfillSubscriptions
+	bits *backfillLeaderboard
+
+	// when the channel was last known to be listened to
+	listened time.Time
+}
+
+// backfillSubscriptions are the subscriptions of a channel by user id.
+type backfillSubscriptions struct {
+	at   time.Time
+	subs map[string]*HelixSubscription
+}
+
+// backfillLeaderboard is the all time bits leaderboard of a channel.
+type backfillLeaderboard struct {
+	at     time.Time
+	scores map[string]*HelixLeaderboardEntry
+	// set when every cheerer fit on the leaderboard, so anyone missing
+	// from it hadn't cheered
+	complete bool
+}
+
+// HelixSubscription is a subscription on a helix subscriptions page.
+type HelixSubscription struct {
+	UserID     string `json:"user_id"`
+	UserLogin  string `json:"user_login"`
+	UserName   string `json:"user_name"`
+	GifterID   string `json:"gifter_id"`
+	GifterName string `json:"gifter_name"`
+	IsGift     bool   `json:"is_gift"`
+	Tier       string `json:"tier"`
+	PlanName   string `json:"plan_name"`
+}
+
+// HelixSubscriptionsResp is a page of helix subscriptions.
+type HelixSubscriptionsResp struct {
+	Data       []*HelixSubscription `json:"data"`
+	Total      int                  `json:"total"`
+	Pagination struct {
+		Cursor string `json:"cursor"`
+	} `json:"pagination"`
+}
+
+// HelixLeaderboardEntry is a user on the helix bits leaderboard.
+type HelixLeaderboardEntry struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+	Score     int    `json:"score"`
+}
+
+// HelixLeaderboardResp is the helix bits leaderboard.
+type HelixLeaderboardResp struct {
+	Data  []*HelixLeaderboardEntry `json:"data"`
+	Total int                      `json:"total"`
+}
+
+// NewBackfill returns a new backfill, loading baselines from the
+// database as channels are listened to.
+func NewBackfill(c *config.Config, db *database.Database, t *Twitch) *Backfill {
+	return &Backfill{
+		config:   c,
+		database: db,
+		twitch:   t,
+
+		baselines: make(map[string]*backfillBaseline),
+		clients:   make(map[string]*helix.Client),
+	}
+}
+
+// Baseline records the subscriptions and bits leaderboard of a channel
+// listened to for the first time, for backfilling once it's down. A
+// channel with a saved baseline was down since it was last listened
+// to, so that time is backfilled instead. It returns whether the
+// channel has a whole baseline.
+func (b *Backfill) Baseline(channelID string) bool {
+	b.run.Lock()
+	defer b.run.Unlock()
+
+	if previous := b.getBaseline(channelID); previous != nil && !previous.gapFrom().IsZero() {
+		return b.gap(channelID, previous.gapFrom(), time.Now())
+	}
+
+	return b.refresh(channelID)
+}
+
+// Refresh retakes the baseline of a listened channel, so a backfill
+// diffs against what helix reported recently. It returns whether the
+// channel has a whole baseline.
+func (b *Backfill) Refresh(channelID string) bool {
+	b.run.Lock()
+	defer b.run.Unlock()
+
+	return b.refresh(channelID)
+}
+
+// Listened records that channels were listened to at a time, where the
+// gap backfilled after a restart starts.
+func (b *Backfill) Listened(channelIDs []string, at time.Time) {
+	for _, channelID := range channelIDs {
+		b.mu.Lock()
+		if baseline, ok := b.baselines[channelID]; ok {
+			baseline.listened = at
+		}
+		b.mu.Unlock()
+
+		if err := b.database.SetBaselineListened(channelID, at); err != nil {
+			log.Printf("[ERROR] backfill: channel [%s]: %s", channelID, err)
+		}
+	}
+}
+
+// takes the baseline of a channel, keeping the old part of it for
+// anything that couldn't be read, must be called with b.run held
+func (b *Backfill) refresh(channelID string) bool {
+	ctx, cancel := context.WithTimeout(b.twitch.ctx, backfillTimeout)
+	defer cancel()
+
+	baseline, errs := b.baseline(ctx, channelID)
+	for _, err := range errs {
+		log.Printf("[ERROR] backfill: channel [%s]: baseline: %s", channelID, err)
+	}
+
+	if previous := b.getBaseline(channelID); previous != nil {
+		if baseline.subs == nil {
+			baseline.subs = previous.subs
+		}
+		if baseline.bits == nil {
+			baseline.bits = previous.bits
+		}
+	}
+	if baseline.subs == nil && baseline.bits == nil {
+		return false
+	}
+
+	baseline.listened = time.Now()
+	b.setBaseline(channelID, baseline)
+	log.Printf("[INFO] backfill: channel [%s]: baseline taken", channelID)
+
+	return baseline.subs != nil && baseline.bits != nil
+}
+
+// Gap backfills a channel that wasn't listened to between from and to,
+// recording the gap with what was recovered. It returns whether the
+// channel has a whole baseline afterwards.
+func (b *Backfill) Gap(channelID string, from time.Time, to time.Time) bool {
+	b.run.Lock()
+	defer b.run.Unlock()
+
+	return b.gap(channelID, from, to)
+}
+
+// backfills a gap, must be called with b.run held
+func (b *Backfill) gap(channelID string, from time.Time, to time.Time) bool {
+	log.Printf("[INFO] backfill: channel [%s]: down for %s: backfilling", channelID, to.Sub(from).Round(time.Second))
+
+	ctx, cancel := context.WithTimeout(b.twitch.ctx, backfillTimeout)
+	defer cancel()
+
+	gap := &database.Gap{
+		ChannelID: channelID,
+		From:      from,
+		To:        to,
+	}
+
+	// follows are timestamped, so page back to the start of the gap
+	follows, err := b.backfillFollows(ctx, channelID, from)
+	if err != nil {
+		gap.Errors = append(gap.Errors, fmt.Sprintf("follows: %s", err))
+	}
+	gap.Follows = follows
+
+	// diff subs and cheers against the last baseline, the new
+	// baseline is taken from the same responses
+	previous := b.getBaseline(channelID)
+	current, errs := b.baseline(ctx, channelID)
+	for _, err := range errs {
+		gap.Errors = append(gap.Errors, err.Error())
+	}
+
+	if previous == nil {
+		previous = &backfillBaseline{}
+	}
+
+	if previous.subs != nil && current.subs != nil {
+		subs, errs := b.backfillSubscriptions(channelID, from, previous.subs, current.subs)
+		for _, err := range errs {
+			gap.Errors = append(gap.Errors, fmt.Sprintf("subscriptions: %s", err))
+		}
+		gap.Subscriptions = subs
+	}
+
+	if previous.bits != nil && current.bits != nil {
+		bits, errs := b.backfillBits(channelID, from, previous.bits, current.bits)
+		for _, err := range errs {
+			gap.Errors = append(gap.Errors, fmt.Sprintf("bits: %s", err))
+		}
+		gap.Bits = bits
+	}
+
+	// keep the old part of the baseline for anything that couldn't be read
+	if current.subs == nil {
+		current.subs = previous.subs
+	}
+	if current.bits == nil {
+		current.bits = previous.bits
+	}
+	if current.subs != nil || current.bits != nil {
+		current.listened = to
+		b.setBaseline(channelID, current)
+	}
+
+	for _, err := range gap.Errors {
+		log.Printf("[ERROR] backfill: channel [%s]: %s", channelID, err)
+	}
+	log.Printf("[INFO] backfill: channel [%s]: backfilled %d follows, %d subs, %d cheers", channelID, gap.Follows, gap.Subscriptions, gap.Bits)
+
+	b.twitch.metrics.Add(MetricBackfilled+EventKindFollow, int64(gap.Follows))
+	b.twitch.metrics.Add(MetricBackfilled+EventKindSubscription, int64(gap.Subscriptions))
+	b.twitch.metrics.Add(MetricBackfilled+EventKindBits, int64(gap.Bits))
+
+	// record the gap
+	if err := b.database.AddGap(gap); err != nil {
+		log.Printf("[ERROR] backfill: channel [%s]: %s", channelID, err)
+	}
+
+	return current.subs != nil && current.bits != nil
+}
+
+// adds the follows since the start of a gap, returning how many were new
+func (b *Backfill) backfillFollows(ctx context.Context, channelID string, from time.Time) (int, error) {
+	added := make([]string, 0)
+
+	if err := b.twitch.pageFollowers(ctx, channelID, func(f *database.Follower) bool {
+		// followers are newest first, so stop at the gap
+		if f.Timestamp.Before(from) {
+			return false
+		}
+
+		if err := b.twitch.events.Publish(&events.Follow{
+			Meta: events.Meta{
+				Source:         events.SourceBackfill,
+				ChannelID:      channelID,
+				Timestamp:      time.Now(),
+				Backfilled:     true,
+				BackfilledFrom: from,
+			},
+			UserID: f.FollowerID,
+		}); err != nil {
+			if !b.twitch.duplicate(EventKindFollow, err) {
+				log.Printf("[ERROR] backfill: channel [%s]: add follower [%s]: %s", channelID, f.FollowerID, err)
+			}
+			return true
+		}
+
+		added = append(added, f.FollowerID)
+		return true
+	}); err != nil {
+		return len(added), err
+	}
+
+	// cache the follower profiles
+	b.twitch.ResolveUsersAsync(added...)
+
+	return len(added), nil
+}
+
+// adds the subscriptions that started since the baseline and weren't
+// delivered by pub sub, returning how many were added
+func (b *Backfill) backfillSubscriptions(channelID string, from time.Time, previous *backfillSubscriptions, current *backfillSubscriptions) (int, []error) {
+	added := make([]string, 0)
+	errs := make([]error, 0)
+
+	for userID, sub := range current.subs {
+		if _, ok := previous.subs[userID]; ok {
+			continue
+		}
+
+		// skip subs pub sub delivered
+		seen, err := b.database.HasSubscriberSince(channelID, userID, previous.at)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen {
+			continue
+		}
+
+		// helix doesn't say when or how long, so the sub is a
+		// first month timestamped when it's recovered, so apps that
+		// synced during the gap still see it
+		subscription := &events.Subscription{
+			Meta: events.Meta{
+				MessageID:      backfillMessageID(EventKindSubscription, channelID, userID, previous.at),
+				Source:         events.SourceBackfill,
+				ChannelID:      channelID,
+				Timestamp:      time.Now(),
+				Backfilled:     true,
+				BackfilledFrom: from,
+			},
+			UserID:   userID,
+			UserName: sub.UserName,
+			Tier:     sub.Tier,
+			PlanName: sub.PlanName,
+			Months:   1,
+			Context:  "sub",
+		}
+
+		if sub.IsGift {
+			subscription.Context = "subgift"
+			subscription.IsGift = true
+			subscription.GifterID = sub.GifterID
+			subscription.GifterName = sub.GifterName
+		}
+
+		if err := b.twitch.events.Publish(subscription); err != nil {
+			if !b.twitch.duplicate(EventKindSubscription, err) {
+				errs = append(errs, fmt.Errorf("add sub [%s]: %s", userID, err))
+			}
+			continue
+		}
+
+		added = append(added, userID)
+	}
+
+	// cache the subscriber profiles
+	b.twitch.ResolveUsersAsync(added...)
+
+	return len(added), errs
+}
+
+// adds the bits cheered since the baseline that pub sub didn't deliver,
+// one cheer per cheerer, returning how many were added
+func (b *Backfill) backfillBits(channelID string, from time.Time, previous *backfillLeaderboard, current *backfillLeaderboard) (int, []error) {
+	added := make([]string, 0)
+	errs := make([]error, 0)
+
+	for userID, entry := range current.scores {
+		// a cheerer new to a full leaderboard has no known score to
+		// diff against
+		score := 0
+		if prev, ok := previous.scores[userID]; ok {
+			score = prev.Score
+		} else if !previous.complete {
+			continue
+		}
+
+		cheered := entry.Score - score
+		if cheered <= 0 {
+			continue
+		}
+
+		// leave out bits pub sub delivered
+		saved, err := b.database.GetBitsUsedSince(channelID, userID, previous.at)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		missed := cheered - saved
+		if missed <= 0 {
+			continue
+		}
+
+		if err := b.twitch.events.Publish(&events.Cheer{
+			Meta: events.Meta{
+				MessageID:      backfillMessageID(EventKindBits, channelID, userID, previous.at),
+				Source:         events.SourceBackfill,
+				ChannelID:      channelID,
+				Timestamp:      time.Now(),
+				Backfilled:     true,
+				BackfilledFrom: from,
+			},
+			UserID:   userID,
+			UserName: entry.UserLogin,
+			Bits:     missed,
+			Context:  "cheer",
+		}); err != nil {
+			if !b.twitch.duplicate(EventKindBits, err) {
+				errs = append(errs, fmt.Errorf("add bits [%s]: %s", userID, err))
+			}
+			continue
+		}
+
+		added = append(added, userID)
+	}
+
+	// cache the cheerer profiles
+	b.twitch.ResolveUsersAsync(added...)
+
+	return len(added), errs
+}
+
+// reads the subscriptions and bits leaderboard of a channel
+func (b *Backfill) baseline(ctx context.Context, channelID string) (*backfillBaseline, []error) {
+	baseline := &backfillBaseline{}
+	errs := make([]error, 0)
+
+	subs, err := b.getSubscriptions(ctx, channelID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("subscriptions: %s", err))
+	}
+	baseline.subs = subs
+
+	bits, err := b.getLeaderboard(ctx, channelID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("bits leaderboard: %s", err))
+	}
+	baseline.bits = bits
+
+	return baseline, errs
+}
+
+// returns the current subscriptions of a channel
+func (b *Backfill) getSubscriptions(ctx context.Context, channelID string) (*backfillSubscriptions, error) {
+	subs := &backfillSubscriptions{
+		// taken before reading, so events during the read are
+		// left out of a backfill rather than counted twice
+		at:   time.Now(),
+		subs: make(map[string]*HelixSubscription),
+	}
+	client := b.client(channelID)
+
+	url := strings.Join([]string{TwitchHelix.Url(b.config.APIURL()), TWITCH_HELIX_SUBSCRIPTIONS_URL, channelID}, "")
+	cursor := ""
+
+	for {
+		// url with possible pagination cursor
+		urlPaginate := url
+		if len(cursor) > 0 {
+			urlPaginate += "&after=" + cursor
+		}
+
+		body, err := client.Get(ctx, urlPaginate, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		subsResp := &HelixSubscriptionsResp{}
+		if err := json.NewDecoder(bytes.NewReader(body)).Decode(subsResp); err != nil {
+			return nil, fmt.Errorf("body decode: %s", err)
+		}
+
+		for _, sub := range subsResp.Data {
+			subs.subs[sub.UserID] = sub
+		}
+
+		cursor = subsResp.Pagination.Cursor
+		if len(subsResp.Data) == 0 || len(cursor) == 0 {
+			return subs, nil
+		}
+	}
+}
+
+// returns the all time bits leaderboard of a channel
+func (b *Backfill) getLeaderboard(ctx context.Context, channelID string) (*backfillLeaderboard, error) {
+	at := time.Now()
+	url := strings.Join([]string{TwitchHelix.Url(b.config.APIURL()), TWITCH_HELIX_BITS_LEADERBOARD_URL, strconv.Itoa(helixLeaderboardMax)}, "")
+
+	body, err := b.client(channelID).Get(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboardResp := &HelixLeaderboardResp{}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(leaderboardResp); err != nil {
+		return nil, fmt.Errorf("body decode: %s", err)
+	}
+
+	leaderboard := &backfillLeaderboard{
+		at:       at,
+		scores:   make(map[string]*HelixLeaderboardEntry),
+		complete: len(leaderboardResp.Data) < helixLeaderboardMax,
+	}
+	for _, entry := range leaderboardResp.Data {
+		leaderboard.scores[entry.UserID] = entry
+	}
+
+	return leaderboard, nil
+}
+
+// returns the helix client reading with the channel token
+func (b *Backfill) client(channelID string) *helix.Client {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	client, ok := b.clients[channelID]
+	if !ok {
+		client = helix.NewClient(b.config.TwitchClientID, &ChannelTokenSource{
+			tokens:    b.twitch.tokens,
+			channelID: channelID,
+		})
+		b.clients[channelID] = client
+	}
+
+	return client
+}
+
+// returns the last baseline of a channel, loading it from the database
+// the first time, or nil without one
+func (b *Backfill) getBaseline(channelID string) *backfillBaseline {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if baseline, ok := b.baselines[channelID]; ok {
+		return baseline
+	}
+
+	saved, err := b.database.GetBaseline(channelID)
+	if err != nil {
+		log.Printf("[ERROR] backfill: channel [%s]: %s", channelID, err)
+		return nil
+	}
+	if saved == nil {
+		return nil
+	}
+
+	baseline := backfillBaselineFromDatabase(saved)
+	b.baselines[channelID] = baseline
+
+	return baseline
+}
+
+// replaces the baseline of a channel, saving it to the database
+func (b *Backfill) setBaseline(channelID string, baseline *backfillBaseline) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.baselines[channelID] = baseline
+
+	if err := b.database.SetBaseline(baseline.toDatabase(channelID)); err != nil {
+		log.Printf("[ERROR] backfill: channel [%s]: %s", channelID, err)
+	}
+}
+
+// returns where the gap after a restart starts, when the channel was
+// last known to be listened to, or for baselines saved without it, when
+// the baseline was read
+func (b *backfillBaseline) gapFrom() time.Time {
+	if !b.listened.IsZero() {
+		return b.listened
+	}
+
+	return b.since()
+}
+
+// returns the earliest time either part of the baseline was read, or
+// the zero time with neither
+func (b *backfillBaseline) since() time.Time {
+	var since time.Time
+	if b.subs != nil {
+		since = b.subs.at
+	}
+	if b.bits != nil && (since.IsZero() || b.bits.at.Before(since)) {
+		since = b.bits.at
+	}
+
+	return since
+}
+
+// returns the baseline in its database form
+func (b *backfillBaseline) toDatabase(channelID string) *database.Baseline {
+	baseline := &database.Baseline{
+		ChannelID: channelID,
+		Listened:  b.listened,
+	}
+
+	if b.subs != nil {
+		baseline.Subscriptions = &database.BaselineSubscriptions{
+			At:   b.subs.at,
+			Subs: make([]*database.BaselineSubscription, 0, len(b.subs.subs)),
+		}
+		for _, sub := range b.subs.subs {
+			baseline.Subscriptions.Subs = append(baseline.Subscriptions.Subs, &database.BaselineSubscription{
+				UserID:     sub.UserID,
+				UserLogin:  sub.UserLogin,
+				UserName:   sub.UserName,
+				GifterID:   sub.GifterID,
+				GifterName: sub.GifterName,
+				IsGift:     sub.IsGift,
+				Tier:       sub.Tier,
+				PlanName:   sub.PlanName,
+			})
+		}
+	}
+
+	if b.bits != nil {
+		baseline.Bits = &database.BaselineBits{
+			At:       b.bits.at,
+			Scores:   make([]*database.BaselineScore, 0, len(b.bits.scores)),
+			Complete: b.bits.complete,
+		}
+		for _, entry := range b.bits.scores {
+			baseline.Bits.Scores = append(baseline.Bits.Scores, &database.BaselineScore{
+				UserID:    entry.UserID,
+				UserLogin: entry.UserLogin,
+				UserName:  entry.UserName,
+				Score:     entry.Score,
+			})
+		}
+	}
+
+	return baseline
+}
+
+// returns a baseline loaded from the database
+func backfillBaselineFromDatabase(saved *database.Baseline) *backfillBaseline {
+	baseline := &backfillBaseline{
+		listened: saved.Listened,
+	}
+
+	if saved.Subscriptions != nil {
+		baseline.subs = &backfillSubscriptions{
+			at:   saved.Subscriptions.At,
+			subs: make(map[string]*HelixSubscription),
+		}
+		for _, sub := range saved.Subscriptions.Subs {
+			baseline.subs.subs[sub.UserID] = &HelixSubscription{
+				UserID:     sub.UserID,
+				UserLogin:  sub.UserLogin,
+				UserName:   sub.UserName,
+				GifterID:   sub.GifterID,
+				GifterName: sub.GifterName,
+				IsGift:     sub.IsGift,
+				Tier:       sub.Tier,
+				PlanName:   sub.PlanName,
+			}
+		}
+	}
+
+	if saved.Bits != nil {
+		baseline.bits = &backfillLeaderboard{
+			at:       saved.Bits.At,
+			scores:   make(map[string]*HelixLeaderboardEntry),
+			complete: saved.Bits.Complete,
+		}
+		for _, score := range saved.Bits.Scores {
+			baseline.bits.scores[score.UserID] = &HelixLeaderboardEntry{
+				UserID:    score.UserID,
+				UserLogin: score.UserLogin,
+				UserName:  score.UserName,
+				Score:     score.Score,
+			}
+		}
+	}
+
+	return baseline
+}
+
+// returns the message id of a backfilled event, the same for every
+// backfill diffed against the same baseline
+func backfillMessageID(kind string, channelID string, userID string, since time.Time) string {
+	return strings.Join([]string{"backfill", kind, channelID, userID, strconv.FormatInt(since.Unix(), 10)}, ".")
+}
@@ -0,0 +1,166 @@
+package twitch
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+var (
+	deadLetterFlushPeriod = 30 * time.Second
+	maxUnsavedDeadLetters = 1000
+)
+
+// DeadLetterRetry is the result of retrying dead letters.
+type DeadLetterRetry struct {
+	Retried int `json:"retried"`
+	// dead letters that failed again, with the new error
+	Failed []*database.DeadLetter `json:"failed"`
+}
+
+// keeps an event that couldn't be decoded or saved. When the database
+// is what failed, the dead letter is held in memory until it's back.
+// Events already saved are never kept, since retrying them can't
+// succeed.
+func (t *Twitch) deadLetter(deadLetter *database.DeadLetter, err error) {
+	if err == database.ErrDuplicate {
+		return
+	}
+
+	deadLetter.ID = bson.NewObjectId()
+	deadLetter.Error = err.Error()
+	deadLetter.Timestamp = time.Now()
+
+	if err := t.database.AddDeadLetter(deadLetter); err != nil {
+		log.Printf("[ERROR] dead letters: %s: holding until the database is back", err)
+		t.holdDeadLetter(deadLetter)
+	}
+}
+
+// holds a dead letter that couldn't be saved, dropping the oldest to
+// the log once too many are held
+func (t *Twitch) holdDeadLetter(deadLetter *database.DeadLetter) {
+	t.deadLettersMu.Lock()
+	defer t.deadLettersMu.Unlock()
+
+	if len(t.unsavedDeadLetters) >= maxUnsavedDeadLetters {
+		dropped := t.unsavedDeadLetters[0]
+		log.Printf("[ERROR] dead letters: dropping %s [%s]: %s", dropped.Source, dropped.Topic, dropped.Payload)
+		t.unsavedDeadLetters = t.unsavedDeadLetters[1:]
+	}
+
+	t.unsavedDeadLetters = append(t.unsavedDeadLetters, deadLetter)
+}
+
+// saves held dead letters every flush period
+func (t *Twitch) runDeadLetterFlush() {
+	ticker := time.NewTicker(deadLetterFlushPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+			t.flushDeadLetters()
+		}
+	}
+}
+
+// saves held dead letters in order, stopping at the first failure
+func (t *Twitch) flushDeadLetters() {
+	t.deadLettersMu.Lock()
+	defer t.deadLettersMu.Unlock()
+
+	for len(t.unsavedDeadLetters) > 0 {
+		if err := t.database.AddDeadLetter(t.unsavedDeadLetters[0]); err != nil {
+			log.Printf("[ERROR] dead letters: %d still held: %s", len(t.unsavedDeadLetters), err)
+			return
+		}
+		t.unsavedDeadLetters = t.unsavedDeadLetters[1:]
+	}
+}
+
+// UnsavedDeadLetters returns how many dead letters are held in memory
+// waiting for the database.
+func (t *Twitch) UnsavedDeadLetters() int {
+	t.deadLettersMu.Lock()
+	defer t.deadLettersMu.Unlock()
+
+	return len(t.unsavedDeadLetters)
+}
+
+// RetryDeadLetter handles a dead letter again, removing it once it's
+// handled. A dead letter that fails again is kept with the new error.
+func (t *Twitch) RetryDeadLetter(id string) (*DeadLetterRetry, error) {
+	deadLetter, err := t.database.GetDeadLetter(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.retryDeadLetters([]*database.DeadLetter{deadLetter})
+}
+
+// RetryDeadLetters handles every dead letter again.
+func (t *Twitch) RetryDeadLetters() (*DeadLetterRetry, error) {
+	deadLetters, err := t.database.GetDeadLetters(0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.retryDeadLetters(deadLetters)
+}
+
+// retries dead letters in order
+func (t *Twitch) retryDeadLetters(deadLetters []*database.DeadLetter) (*DeadLetterRetry, error) {
+	retry := &DeadLetterRetry{
+		Failed: make([]*database.DeadLetter, 0),
+	}
+
+	for _, deadLetter := range deadLetters {
+		// saved since it failed, so it's handled
+		retryErr := t.handleDeadLetter(deadLetter)
+		if retryErr == database.ErrDuplicate {
+			retryErr = nil
+		}
+
+		if retryErr != nil {
+			log.Printf("[ERROR] dead letters: retry [%s]: %s", deadLetter.ID.Hex(), retryErr)
+
+			// keep it with the new error
+			if err := t.database.UpdateDeadLetterAttempt(deadLetter.ID, retryErr.Error()); err != nil {
+				return retry, err
+			}
+			deadLetter.Error = retryErr.Error()
+			deadLetter.Attempts++
+			deadLetter.LastAttempt = time.Now()
+
+			retry.Failed = append(retry.Failed, deadLetter)
+			continue
+		}
+
+		if err := t.database.RemoveDeadLetter(deadLetter.ID.Hex()); err != nil {
+			return retry, err
+		}
+		retry.Retried++
+	}
+
+	return retry, nil
+}
+
+// hands a dead letter back to the handler of its source
+func (t *Twitch) handleDeadLetter(deadLetter *database.DeadLetter) error {
+	switch deadLetter.Source {
+	case database.DeadLetterSourcePubSub:
+		return t.pubsub.handleMessage(deadLetter.Topic, deadLetter.Payload)
+	case database.DeadLetterSourceEventSub:
+		return t.handleEventSubEvent(EventSubSubscriptionType(deadLetter.Topic), deadLetter.MessageID, deadLetter.SentAt, json.RawMessage(deadLetter.Payload))
+	default:
+		return fmt.Errorf("unknown dead letter source: %s", deadLetter.Source)
+	}
+}
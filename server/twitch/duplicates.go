@@ -0,0 +1,49 @@
+package twitch
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+// kinds of events saved from twitch
+const (
+	EventKindSubscription = "subscriptions"
+	EventKindGift         = "gifts"
+	EventKindBits         = "bits"
+	EventKindCommerce     = "commerce"
+	EventKindRedemption   = "redemptions"
+	EventKindFollow       = "follows"
+	EventKindRaid         = "raids"
+)
+
+// MetricDuplicates prefixes the counters of duplicate events dropped,
+// e.g. duplicates.bits.
+const MetricDuplicates = "duplicates."
+
+// checks if saving an event failed because it was already saved,
+// counting it if so
+func (t *Twitch) duplicate(kind string, err error) bool {
+	if err != database.ErrDuplicate {
+		return false
+	}
+
+	log.Printf("[INFO] dropping duplicate %s event", kind)
+	t.metrics.Inc(MetricDuplicates + kind)
+
+	return true
+}
+
+// returns the id of a pub sub message. Twitch only sends a message id
+// with some topics, for the rest a redelivered message is the same
+// message so its hash is used instead.
+func pubsubMessageID(topic string, message string, messageID string) string {
+	if len(messageID) > 0 {
+		return messageID
+	}
+
+	sum := sha1.Sum([]byte(topic + "\n" + message))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package twitch
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/metrics"
+)
+
+func TestPubSubMessageID(t *testing.T) {
+	tests := []struct {
+		name      string
+		topic     string
+		message   string
+		messageID string
+		want      string
+	}{
+		{
+			name:      "twitch message id",
+			topic:     "channel-bits-events-v1.1",
+			message:   `{"bits_used":100}`,
+			messageID: "message-1",
+			want:      "message-1",
+		},
+		{
+			name:    "hashed message",
+			topic:   "channel-subscribe-events-v1.1",
+			message: `{"user_id":"2"}`,
+			want:    "6bb2f7833e8b7c8f080bd1762a3202f78031e44e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pubsubMessageID(tt.topic, tt.message, tt.messageID); got != tt.want {
+				t.Errorf("pubsubMessageID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPubSubMessageIDRedelivery(t *testing.T) {
+	topic := "channel-subscribe-events-v1.1"
+	message := `{"user_id":"2"}`
+
+	tests := []struct {
+		name    string
+		topic   string
+		message string
+		same    bool
+	}{
+		{name: "redelivered", topic: topic, message: message, same: true},
+		{name: "other message", topic: topic, message: `{"user_id":"3"}`, same: false},
+		{name: "other channel", topic: "channel-subscribe-events-v1.2", message: message, same: false},
+	}
+
+	first := pubsubMessageID(topic, message, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pubsubMessageID(tt.topic, tt.message, "")
+			if (got == first) != tt.same {
+				t.Errorf("pubsubMessageID() = %s, first %s, want same %t", got, first, tt.same)
+			}
+		})
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	tests := []struct {
+		name  string
+		err   error
+		want  bool
+		count int64
+	}{
+		{name: "duplicate", err: database.ErrDuplicate, want: true, count: 1},
+		{name: "other error", err: fmt.Errorf("unable to save"), want: false, count: 0},
+		{name: "no error", err: nil, want: false, count: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := &Twitch{metrics: metrics.NewMetrics()}
+
+			if got := tw.duplicate(EventKindBits, tt.err); got != tt.want {
+				t.Errorf("duplicate() = %t, want %t", got, tt.want)
+			}
+			if got := tw.metrics.Counters()[MetricDuplicates+EventKindBits]; got != tt.count {
+				t.Errorf("duplicates counted = %d, want %d", got, tt.count)
+			}
+		})
+	}
+}
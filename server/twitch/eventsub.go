@@ -0,0 +1,351 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/codephobia/twitch-eos-thanks/backoff"
+
+	"github.com/codephobia/twitch-eos-thanks/server/config"
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+var (
+	eventsubMaxMessageSize = int64(64 * 1024)
+	eventsubKeepaliveGrace = 5 * time.Second
+	eventsubDialTimeout    = 10 * time.Second
+)
+
+// EventSub is an eventsub websocket manager for a twitch channel.
+type EventSub struct {
+	config   *config.Config
+	database *database.Database
+	twitch   *Twitch
+	channel  *config.Channel
+
+	client *http.Client
+
+	mu           sync.Mutex
+	conn         *websocket.Conn
+	oldConn      *websocket.Conn
+	sessionID    string
+	reconnecting bool
+
+	keepaliveTimer   *time.Timer
+	keepaliveTimeout time.Duration
+
+	Backoff *backoff.Backoff
+}
+
+// NewEventSub returns a new eventsub for the given channel.
+func NewEventSub(c *config.Config, db *database.Database, t *Twitch, channel *config.Channel) *EventSub {
+	return &EventSub{
+		config:   c,
+		database: db,
+		twitch:   t,
+		channel:  channel,
+
+		client: &http.Client{},
+
+		Backoff: &backoff.Backoff{
+			Min:    backoffMin,
+			Max:    backoffMax,
+			Factor: backoffFactor,
+			Jitter: backoffJitter,
+		},
+	}
+}
+
+// Init initializes the eventsub listener.
+func (e *EventSub) Init() error {
+	log.Printf("[INFO] eventsub: channel [%s]: initializing", e.channel.ID)
+
+	// connect to twitch eventsub
+	conn, err := e.connect(e.config.EventSubURL())
+	if err != nil {
+		return fmt.Errorf("connect: %s", err)
+	}
+
+	// save connection
+	e.mu.Lock()
+	e.conn = conn
+	e.mu.Unlock()
+
+	// enable read
+	go e.ReadPump(conn)
+
+	// return with no error
+	return nil
+}
+
+// connect to twitch eventsub
+func (e *EventSub) connect(url string) (*websocket.Conn, error) {
+	log.Printf("[INFO] eventsub: connecting")
+
+	ctx, cancel := context.WithTimeout(e.twitch.ctx, eventsubDialTimeout)
+	defer cancel()
+
+	// dial connection
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial connection: %s", err)
+	}
+
+	return conn, nil
+}
+
+// ReadPump reads incoming messages on a websocket connection.
+func (e *EventSub) ReadPump(conn *websocket.Conn) {
+	defer func() {
+		log.Printf("[INFO] eventsub: closing read")
+		conn.Close()
+	}()
+
+	conn.SetReadLimit(eventsubMaxMessageSize)
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			// only reconnect if this is still the active connection
+			if e.isCurrent(conn) {
+				log.Printf("[ERROR] eventsub: read: %s", err)
+				e.reconnect()
+			}
+			return
+		}
+
+		// convert bytes to message
+		msg, err := NewEventSubMessage(message)
+		if err != nil {
+			log.Printf("[ERROR] eventsub message: %s", err)
+			continue
+		}
+
+		// handle message
+		e.handleWSMessage(conn, msg)
+	}
+}
+
+// check if a connection is the active connection
+func (e *EventSub) isCurrent(conn *websocket.Conn) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return conn == e.conn
+}
+
+// check if a connection is the one being migrated away from, which
+// twitch keeps delivering to until the new one is welcomed
+func (e *EventSub) isOld(conn *websocket.Conn) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return conn == e.oldConn
+}
+
+// handle incoming websocket messages
+func (e *EventSub) handleWSMessage(conn *websocket.Conn, msg *EventSubMessage) {
+	// the connection being migrated away from only delivers events,
+	// the session is moving to the new one
+	if e.isOld(conn) {
+		switch msg.Metadata.MessageType {
+		case EventSubTypeNotification:
+			e.handleNotification(msg)
+		case EventSubTypeRevocation:
+			e.twitch.HandleEventSubRevocation(msg.Payload.Subscription)
+		}
+		return
+	}
+
+	// ignore anything arriving on a connection that was closed
+	if !e.isCurrent(conn) {
+		return
+	}
+
+	// any message counts as a keepalive
+	e.resetKeepalive()
+
+	switch msg.Metadata.MessageType {
+	case EventSubTypeWelcome:
+		e.handleWelcome(msg.Payload.Session)
+	case EventSubTypeKeepalive:
+		return
+	case EventSubTypeReconnect:
+		log.Printf("[INFO] eventsub: reconnect alert received")
+		e.migrate(msg.Payload.Session.ReconnectURL)
+	case EventSubTypeNotification:
+		e.handleNotification(msg)
+	case EventSubTypeRevocation:
+		e.twitch.HandleEventSubRevocation(msg.Payload.Subscription)
+	}
+}
+
+// handle a session welcome message
+func (e *EventSub) handleWelcome(session *EventSubSession) {
+	log.Printf("[INFO] eventsub: session welcome: %s", session.ID)
+
+	e.mu.Lock()
+	e.sessionID = session.ID
+	e.keepaliveTimeout = time.Duration(session.KeepaliveTimeoutSeconds) * time.Second
+	oldConn := e.oldConn
+	e.oldConn = nil
+	e.mu.Unlock()
+
+	// start keepalive check with the session timeout
+	e.resetKeepalive()
+
+	// subscriptions carry over when twitch asks us to reconnect,
+	// so we only need to close the old connection
+	if oldConn != nil {
+		oldConn.Close()
+		return
+	}
+
+	// create subscriptions on the new session, starting over on a new
+	// session after a backoff if they can't be, twitch drops the ones
+	// made with the old session once it's closed
+	if err := e.createSubscriptions(session.ID); err != nil {
+		log.Printf("[ERROR] eventsub: subscriptions: %s", err)
+		e.reconnect()
+		return
+	}
+
+	// the backoff only resets once the session is subscribed
+	e.mu.Lock()
+	e.Backoff.Reset()
+	e.mu.Unlock()
+}
+
+// handle a websocket message of type notification
+func (e *EventSub) handleNotification(msg *EventSubMessage) {
+	e.twitch.HandleEventSubNotification(msg.Metadata.SubscriptionType, msg.Metadata.MessageID, msg.Metadata.MessageTimestamp, msg.Payload.Event)
+}
+
+// resets the keepalive timer, reconnecting if it ever lapses
+func (e *EventSub) resetKeepalive() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// wait for the welcome message before checking keepalives
+	if e.keepaliveTimeout == 0 {
+		return
+	}
+
+	// stop the previous timer
+	if e.keepaliveTimer != nil {
+		e.keepaliveTimer.Stop()
+	}
+
+	e.keepaliveTimer = time.AfterFunc(e.keepaliveTimeout+eventsubKeepaliveGrace, func() {
+		log.Printf("[INFO] eventsub: keepalive timeout: reconnecting")
+		e.reconnect()
+	})
+}
+
+// moves to the reconnect url twitch sent us, keeping our subscriptions
+func (e *EventSub) migrate(url string) {
+	log.Printf("[INFO] eventsub: migrating session")
+
+	// connect to the new url
+	conn, err := e.connect(url)
+	if err != nil {
+		log.Printf("[ERROR] eventsub: migrate: %s", err)
+		e.reconnect()
+		return
+	}
+
+	// keep the old connection open until the new one is welcomed
+	e.mu.Lock()
+	e.oldConn = e.conn
+	e.conn = conn
+	e.mu.Unlock()
+
+	// enable read
+	go e.ReadPump(conn)
+}
+
+// reconnects to twitch eventsub with a new session
+func (e *EventSub) reconnect() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// only allow one reconnect at a time, and none once twitch is closed
+	if e.reconnecting || e.twitch.ctx.Err() != nil {
+		return
+	}
+	e.reconnecting = true
+
+	log.Printf("[INFO] eventsub: reconnect")
+
+	// stop keepalive checks until we're welcomed again
+	if e.keepaliveTimer != nil {
+		e.keepaliveTimer.Stop()
+	}
+	e.keepaliveTimeout = 0
+
+	// close the current connections
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+	if e.oldConn != nil {
+		e.oldConn.Close()
+		e.oldConn = nil
+	}
+	e.sessionID = ""
+
+	// wait for backoff before attempting reconnect
+	time.AfterFunc(e.Backoff.Duration(), e.attemptReconnect)
+}
+
+func (e *EventSub) attemptReconnect() {
+	// connect to twitch
+	conn, err := e.connect(e.config.EventSubURL())
+
+	e.mu.Lock()
+	e.reconnecting = false
+	e.mu.Unlock()
+
+	if err != nil {
+		log.Printf("[ERROR] connect: %s", err)
+		e.reconnect()
+		return
+	}
+
+	// save connection, the backoff is reset once it's subscribed
+	e.mu.Lock()
+	e.conn = conn
+	e.mu.Unlock()
+
+	// enable read
+	go e.ReadPump(conn)
+}
+
+// Close closes the eventsub connections on shutdown, twitch must be
+// closed first so they aren't reconnected.
+func (e *EventSub) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	log.Printf("[INFO] eventsub: channel [%s]: closing", e.channel.ID)
+
+	if e.keepaliveTimer != nil {
+		e.keepaliveTimer.Stop()
+	}
+	e.keepaliveTimeout = 0
+
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+	if e.oldConn != nil {
+		e.oldConn.Close()
+		e.oldConn = nil
+	}
+}
@@ -0,0 +1,247 @@
+package twitch
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// EventSubMessage is a message received on the eventsub websocket.
+type EventSubMessage struct {
+	Metadata *EventSubMessageMetadata `json:"metadata"`
+	Payload  *EventSubMessagePayload  `json:"payload"`
+}
+
+// EventSubMessageMetadata is the metadata on an eventsub message.
+type EventSubMessageMetadata struct {
+	MessageID           string                   `json:"message_id"`
+	MessageType         EventSubType             `json:"message_type"`
+	MessageTimestamp    string                   `json:"message_timestamp"`
+	SubscriptionType    EventSubSubscriptionType `json:"subscription_type,omitempty"`
+	SubscriptionVersion string                   `json:"subscription_version,omitempty"`
+}
+
+// EventSubMessagePayload is the payload on an eventsub message.
+type EventSubMessagePayload struct {
+	Session      *EventSubSession      `json:"session,omitempty"`
+	Subscription *EventSubSubscription `json:"subscription,omitempty"`
+	Event        json.RawMessage       `json:"event,omitempty"`
+
+	// only sent on webhook verification requests
+	Challenge string `json:"challenge,omitempty"`
+}
+
+// EventSubSession is the websocket session sent on welcome
+// and reconnect messages.
+type EventSubSession struct {
+	ID                      string `json:"id"`
+	Status                  string `json:"status"`
+	ConnectedAt             string `json:"connected_at"`
+	KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+	ReconnectURL            string `json:"reconnect_url"`
+}
+
+// EventSubSubscription is an eventsub subscription.
+type EventSubSubscription struct {
+	ID        string                         `json:"id,omitempty"`
+	Status    string                         `json:"status,omitempty"`
+	Type      EventSubSubscriptionType       `json:"type"`
+	Version   string                         `json:"version"`
+	Cost      int                            `json:"cost,omitempty"`
+	Condition map[string]string              `json:"condition"`
+	Transport *EventSubSubscriptionTransport `json:"transport"`
+	CreatedAt string                         `json:"created_at,omitempty"`
+}
+
+// EventSubSubscriptionTransport is the transport used by an
+// eventsub subscription.
+type EventSubSubscriptionTransport struct {
+	Method    string `json:"method"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// NewEventSubMessage returns a message from a websocket message.
+func NewEventSubMessage(message []byte) (*EventSubMessage, error) {
+	var msg EventSubMessage
+
+	// unmarshal message bytes
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return nil, err
+	}
+
+	// make sure we have metadata and a payload
+	if msg.Metadata == nil || msg.Payload == nil {
+		return nil, fmt.Errorf("missing metadata or payload")
+	}
+
+	// return new message
+	return &msg, nil
+}
+
+// EventSubSubscribeEvent is the event for a channel.subscribe
+// notification.
+type EventSubSubscribeEvent struct {
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	Tier                 string `json:"tier"`
+	IsGift               bool   `json:"is_gift"`
+}
+
+// NewEventSubSubscribeEvent returns a new subscribe event.
+func NewEventSubSubscribeEvent(event json.RawMessage) (*EventSubSubscribeEvent, error) {
+	var sub EventSubSubscribeEvent
+
+	// unmarshal event
+	if err := json.Unmarshal(event, &sub); err != nil {
+		return nil, err
+	}
+
+	// return sub
+	return &sub, nil
+}
+
+// EventSubSubscriptionMessageEvent is the event for a
+// channel.subscription.message notification.
+type EventSubSubscriptionMessageEvent struct {
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	Tier                 string `json:"tier"`
+	Message              struct {
+		Text   string           `json:"text"`
+		Emotes []*EventSubEmote `json:"emotes"`
+	} `json:"message"`
+	CumulativeMonths int `json:"cumulative_months"`
+	StreakMonths     int `json:"streak_months"`
+	DurationMonths   int `json:"duration_months"`
+}
+
+// NewEventSubSubscriptionMessageEvent returns a new subscription
+// message event.
+func NewEventSubSubscriptionMessageEvent(event json.RawMessage) (*EventSubSubscriptionMessageEvent, error) {
+	var sub EventSubSubscriptionMessageEvent
+
+	// unmarshal event
+	if err := json.Unmarshal(event, &sub); err != nil {
+		return nil, err
+	}
+
+	// return sub
+	return &sub, nil
+}
+
+// EventSubSubscriptionGiftEvent is the event for a
+// channel.subscription.gift notification.
+type EventSubSubscriptionGiftEvent struct {
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	Total                int    `json:"total"`
+	Tier                 string `json:"tier"`
+	CumulativeTotal      int    `json:"cumulative_total"`
+	IsAnonymous          bool   `json:"is_anonymous"`
+}
+
+// NewEventSubSubscriptionGiftEvent returns a new subscription
+// gift event.
+func NewEventSubSubscriptionGiftEvent(event json.RawMessage) (*EventSubSubscriptionGiftEvent, error) {
+	var gift EventSubSubscriptionGiftEvent
+
+	// unmarshal event
+	if err := json.Unmarshal(event, &gift); err != nil {
+		return nil, err
+	}
+
+	// return gift
+	return &gift, nil
+}
+
+// EventSubEmote is a twitch emote contained within an eventsub message.
+type EventSubEmote struct {
+	Begin int    `json:"begin"`
+	End   int    `json:"end"`
+	ID    string `json:"id"`
+}
+
+// EventSubCheerEvent is the event for a channel.cheer notification.
+type EventSubCheerEvent struct {
+	IsAnonymous          bool   `json:"is_anonymous"`
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	Message              string `json:"message"`
+	Bits                 int    `json:"bits"`
+}
+
+// NewEventSubCheerEvent returns a new cheer event.
+func NewEventSubCheerEvent(event json.RawMessage) (*EventSubCheerEvent, error) {
+	var cheer EventSubCheerEvent
+
+	// unmarshal event
+	if err := json.Unmarshal(event, &cheer); err != nil {
+		return nil, err
+	}
+
+	// return cheer
+	return &cheer, nil
+}
+
+// EventSubFollowEvent is the event for a channel.follow notification.
+type EventSubFollowEvent struct {
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	FollowedAt           string `json:"followed_at"`
+}
+
+// NewEventSubFollowEvent returns a new follow event.
+func NewEventSubFollowEvent(event json.RawMessage) (*EventSubFollowEvent, error) {
+	var follow EventSubFollowEvent
+
+	// unmarshal event
+	if err := json.Unmarshal(event, &follow); err != nil {
+		return nil, err
+	}
+
+	// return follow
+	return &follow, nil
+}
+
+// EventSubRaidEvent is the event for a channel.raid notification.
+type EventSubRaidEvent struct {
+	FromBroadcasterUserID    string `json:"from_broadcaster_user_id"`
+	FromBroadcasterUserLogin string `json:"from_broadcaster_user_login"`
+	FromBroadcasterUserName  string `json:"from_broadcaster_user_name"`
+	ToBroadcasterUserID      string `json:"to_broadcaster_user_id"`
+	ToBroadcasterUserLogin   string `json:"to_broadcaster_user_login"`
+	ToBroadcasterUserName    string `json:"to_broadcaster_user_name"`
+	Viewers                  int    `json:"viewers"`
+}
+
+// NewEventSubRaidEvent returns a new raid event.
+func NewEventSubRaidEvent(event json.RawMessage) (*EventSubRaidEvent, error) {
+	var raid EventSubRaidEvent
+
+	// unmarshal event
+	if err := json.Unmarshal(event, &raid); err != nil {
+		return nil, err
+	}
+
+	// return raid
+	return &raid, nil
+}
@@ -0,0 +1,251 @@
+package twitch
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+)
+
+// HandleEventSubNotification publishes the event of an eventsub
+// notification, whether it came over the websocket or a webhook.
+func (t *Twitch) HandleEventSubNotification(subscriptionType EventSubSubscriptionType, messageID string, messageTimestamp string, event json.RawMessage) {
+	// keep anything that couldn't be decoded or saved
+	if err := t.handleEventSubEvent(subscriptionType, messageID, messageTimestamp, event); err != nil {
+		log.Printf("[ERROR] eventsub: %s: %s", subscriptionType, err)
+		t.deadLetter(&database.DeadLetter{
+			Source:    database.DeadLetterSourceEventSub,
+			Topic:     subscriptionType.String(),
+			Payload:   string(event),
+			MessageID: messageID,
+			SentAt:    messageTimestamp,
+		}, err)
+	}
+}
+
+// publishes the event of an eventsub notification, returning an error
+// if it couldn't be decoded or saved
+func (t *Twitch) handleEventSubEvent(subscriptionType EventSubSubscriptionType, messageID string, messageTimestamp string, event json.RawMessage) error {
+	// convert timestamp
+	timestamp, err := time.Parse(time.RFC3339, messageTimestamp)
+	if err != nil {
+		log.Printf("[ERROR] unable to convert eventsub timestamp: %s", err)
+		timestamp = time.Now()
+	}
+
+	// every event carries the notification meta
+	meta := events.Meta{
+		MessageID: messageID,
+		Source:    events.SourceEventSub,
+		Timestamp: timestamp,
+	}
+
+	switch subscriptionType {
+	case EventSubSubscriptionTypeSubscribe:
+		subscription, err := NewEventSubSubscribeEvent(event)
+		if err != nil {
+			return fmt.Errorf("subscribe event: %s", err)
+		}
+
+		// build the subscription, resubs are sent as subscription messages instead
+		meta.ChannelID = subscription.BroadcasterUserID
+		sub := &events.Subscription{
+			Meta:     meta,
+			UserID:   subscription.UserID,
+			UserName: subscription.UserName,
+			Tier:     subscription.Tier,
+			Months:   1,
+			Context:  "sub",
+		}
+
+		// gifted subs are bundled in to the gift event sent before them
+		if subscription.IsGift {
+			sub.Context = "subgift"
+			sub.IsGift = true
+		}
+
+		// publish the subscription
+		if err := t.events.Publish(sub); err != nil && !t.duplicate(EventKindSubscription, err) {
+			return fmt.Errorf("add sub: %s", err)
+		}
+
+		// cache the subscriber profile
+		t.ResolveUsersAsync(subscription.UserID)
+
+		return nil
+	case EventSubSubscriptionTypeSubscriptionMessage:
+		subscription, err := NewEventSubSubscriptionMessageEvent(event)
+		if err != nil {
+			return fmt.Errorf("subscription message event: %s", err)
+		}
+
+		// generate emotes for the event
+		messageEmotes := make([]*events.Emote, 0)
+
+		// loop through sub emotes
+		for _, emote := range subscription.Message.Emotes {
+			id, _ := strconv.Atoi(emote.ID)
+
+			messageEmotes = append(messageEmotes, &events.Emote{
+				Start: emote.Begin,
+				End:   emote.End,
+				ID:    id,
+			})
+		}
+
+		// publish the subscription
+		meta.ChannelID = subscription.BroadcasterUserID
+		if err := t.events.Publish(&events.Subscription{
+			Meta:     meta,
+			UserID:   subscription.UserID,
+			UserName: subscription.UserName,
+			Tier:     subscription.Tier,
+			Months:   subscription.CumulativeMonths,
+			Streak:   subscription.StreakMonths,
+			Context:  "resub",
+			Message: &events.Message{
+				Text:   subscription.Message.Text,
+				Emotes: messageEmotes,
+			},
+
+			MultiMonthDuration: subscription.DurationMonths,
+		}); err != nil && !t.duplicate(EventKindSubscription, err) {
+			return fmt.Errorf("add sub: %s", err)
+		}
+
+		// cache the subscriber profile
+		t.ResolveUsersAsync(subscription.UserID)
+
+		return nil
+	case EventSubSubscriptionTypeSubscriptionGift:
+		gift, err := NewEventSubSubscriptionGiftEvent(event)
+		if err != nil {
+			return fmt.Errorf("subscription gift event: %s", err)
+		}
+
+		// anonymous gifts don't include a user
+		gifterName := gift.UserName
+		if gift.IsAnonymous {
+			gifterName = "Anonymous"
+		}
+
+		// publish the gift
+		meta.ChannelID = gift.BroadcasterUserID
+		if err := t.events.Publish(&events.Gift{
+			Meta:        meta,
+			GifterID:    gift.UserID,
+			GifterName:  gifterName,
+			IsAnonymous: gift.IsAnonymous,
+			Tier:        gift.Tier,
+			Count:       gift.Total,
+		}); err != nil && !t.duplicate(EventKindGift, err) {
+			return fmt.Errorf("add gift: %s", err)
+		}
+
+		return nil
+	case EventSubSubscriptionTypeCheer:
+		cheer, err := NewEventSubCheerEvent(event)
+		if err != nil {
+			return fmt.Errorf("cheer event: %s", err)
+		}
+
+		// anonymous cheers don't include a user
+		userName := cheer.UserName
+		if cheer.IsAnonymous {
+			userName = "Anonymous"
+		}
+
+		// publish the cheer
+		meta.ChannelID = cheer.BroadcasterUserID
+		if err := t.events.Publish(&events.Cheer{
+			Meta:        meta,
+			UserID:      cheer.UserID,
+			UserName:    userName,
+			ChannelName: cheer.BroadcasterUserLogin,
+			Message:     cheer.Message,
+			Bits:        cheer.Bits,
+			Context:     "cheer",
+		}); err != nil && !t.duplicate(EventKindBits, err) {
+			return fmt.Errorf("add bits: %s", err)
+		}
+
+		// cache the cheerer profile
+		t.ResolveUsersAsync(cheer.UserID)
+
+		return nil
+	case EventSubSubscriptionTypeFollow:
+		follow, err := NewEventSubFollowEvent(event)
+		if err != nil {
+			return fmt.Errorf("follow event: %s", err)
+		}
+
+		// convert follow time
+		followedAt, err := time.Parse(time.RFC3339, follow.FollowedAt)
+		if err != nil {
+			log.Printf("[ERROR] unable to convert follow timestamp: %s", err)
+			followedAt = timestamp
+		}
+
+		// publish the follow
+		meta.ChannelID = follow.BroadcasterUserID
+		meta.Timestamp = followedAt
+		if err := t.events.Publish(&events.Follow{
+			Meta:   meta,
+			UserID: follow.UserID,
+		}); err != nil && !t.duplicate(EventKindFollow, err) {
+			return fmt.Errorf("add follower: %s", err)
+		}
+
+		// cache the follower profile
+		t.ResolveUsersAsync(follow.UserID)
+
+		return nil
+	case EventSubSubscriptionTypeRaid:
+		raid, err := NewEventSubRaidEvent(event)
+		if err != nil {
+			return fmt.Errorf("raid event: %s", err)
+		}
+
+		// publish the raid
+		meta.ChannelID = raid.ToBroadcasterUserID
+		if err := t.events.Publish(&events.Raid{
+			Meta:       meta,
+			RaiderID:   raid.FromBroadcasterUserID,
+			RaiderName: raid.FromBroadcasterUserName,
+			Viewers:    raid.Viewers,
+		}); err != nil && !t.duplicate(EventKindRaid, err) {
+			return fmt.Errorf("add raid: %s", err)
+		}
+
+		// cache the raider profile
+		t.ResolveUsersAsync(raid.FromBroadcasterUserID)
+
+		return nil
+	}
+
+	return nil
+}
+
+// HandleEventSubRevocation records an eventsub subscription twitch revoked.
+func (t *Twitch) HandleEventSubRevocation(subscription *EventSubSubscription) {
+	if subscription == nil {
+		return
+	}
+
+	log.Printf("[ERROR] eventsub: subscription revoked: %s: %s", subscription.Type, subscription.Status)
+
+	// add the revocation to the database
+	if err := t.database.AddRevocation(&database.Revocation{
+		SubscriptionID: subscription.ID,
+		Type:           subscription.Type.String(),
+		Status:         subscription.Status,
+		Condition:      subscription.Condition,
+		Timestamp:      time.Now(),
+	}); err != nil {
+		log.Printf("[ERROR] add revocation: %s", err)
+	}
+}
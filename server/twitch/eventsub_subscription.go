@@ -0,0 +1,127 @@
+package twitch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+var (
+	TWITCH_HELIX_EVENTSUB_SUBSCRIPTIONS_URL string = "/eventsub/subscriptions"
+)
+
+// EventSubSubscriptionErrorResp is an invalid response from a
+// subscription request.
+type EventSubSubscriptionErrorResp struct {
+	Error   string `json:"error"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// creates the eventsub subscriptions for the channel on the current session
+func (e *EventSub) createSubscriptions(sessionID string) error {
+	log.Printf("[INFO] eventsub: channel [%s]: creating subscriptions", e.channel.ID)
+
+	channelID := e.channel.ID
+
+	// subscriptions to create
+	subscriptions := []*EventSubSubscription{
+		{
+			Type:      EventSubSubscriptionTypeSubscribe,
+			Version:   "1",
+			Condition: map[string]string{"broadcaster_user_id": channelID},
+		},
+		{
+			Type:      EventSubSubscriptionTypeSubscriptionMessage,
+			Version:   "1",
+			Condition: map[string]string{"broadcaster_user_id": channelID},
+		},
+		{
+			Type:      EventSubSubscriptionTypeSubscriptionGift,
+			Version:   "1",
+			Condition: map[string]string{"broadcaster_user_id": channelID},
+		},
+		{
+			Type:      EventSubSubscriptionTypeCheer,
+			Version:   "1",
+			Condition: map[string]string{"broadcaster_user_id": channelID},
+		},
+		{
+			Type:    EventSubSubscriptionTypeFollow,
+			Version: "2",
+			Condition: map[string]string{
+				"broadcaster_user_id": channelID,
+				"moderator_user_id":   channelID,
+			},
+		},
+	}
+
+	// create each subscription on the session
+	for _, subscription := range subscriptions {
+		subscription.Transport = &EventSubSubscriptionTransport{
+			Method:    "websocket",
+			SessionID: sessionID,
+		}
+
+		if err := e.createSubscription(subscription); err != nil {
+			return fmt.Errorf("%s: %s", subscription.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// sends a create subscription request to helix
+func (e *EventSub) createSubscription(subscription *EventSubSubscription) error {
+	// marshal subscription
+	body, err := json.Marshal(subscription)
+	if err != nil {
+		return fmt.Errorf("error marshalling subscription: %s", err)
+	}
+
+	// build url with version prefix / suffix
+	url := strings.Join([]string{TwitchHelix.Url(e.config.APIURL()), TWITCH_HELIX_EVENTSUB_SUBSCRIPTIONS_URL}, "")
+
+	// create new request
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error generating request: %v", err)
+	}
+
+	// websocket subscriptions require the channel user token
+	oauthToken, _ := e.config.ChannelTokens(e.channel)
+	req.Header.Add("Authorization", bearerPrefix+oauthToken)
+	req.Header.Add("Client-ID", e.config.TwitchClientID)
+	req.Header.Add("Content-Type", "application/json")
+
+	// do post request
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error doing request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// subscription was accepted
+	if resp.StatusCode == http.StatusAccepted {
+		return nil
+	}
+
+	// read body
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading body: %v", err)
+	}
+
+	// unmarshal error
+	var errorResp EventSubSubscriptionErrorResp
+	if err := json.Unmarshal(data, &errorResp); err != nil {
+		return fmt.Errorf("invalid response code: %d", resp.StatusCode)
+	}
+
+	// return response error
+	return fmt.Errorf("invalid response code: %d: %s: %s", resp.StatusCode, errorResp.Error, errorResp.Message)
+}
@@ -0,0 +1,35 @@
+package twitch
+
+// EventSubType is a type of eventsub websocket message.
+type EventSubType string
+
+const (
+	EventSubTypeWelcome      EventSubType = "session_welcome"
+	EventSubTypeKeepalive    EventSubType = "session_keepalive"
+	EventSubTypeReconnect    EventSubType = "session_reconnect"
+	EventSubTypeNotification EventSubType = "notification"
+	EventSubTypeRevocation   EventSubType = "revocation"
+
+	// only sent to webhook transports
+	EventSubTypeVerification EventSubType = "webhook_callback_verification"
+)
+
+func (t EventSubType) String() string {
+	return string(t)
+}
+
+// EventSubSubscriptionType is a twitch eventsub subscription type.
+type EventSubSubscriptionType string
+
+const (
+	EventSubSubscriptionTypeSubscribe           EventSubSubscriptionType = "channel.subscribe"
+	EventSubSubscriptionTypeSubscriptionMessage EventSubSubscriptionType = "channel.subscription.message"
+	EventSubSubscriptionTypeSubscriptionGift    EventSubSubscriptionType = "channel.subscription.gift"
+	EventSubSubscriptionTypeCheer               EventSubSubscriptionType = "channel.cheer"
+	EventSubSubscriptionTypeFollow              EventSubSubscriptionType = "channel.follow"
+	EventSubSubscriptionTypeRaid                EventSubSubscriptionType = "channel.raid"
+)
+
+func (t EventSubSubscriptionType) String() string {
+	return string(t)
+}
@@ -2,6 +2,7 @@ package twitch
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -32,10 +33,37 @@ type FollowerResp struct {
 	} `json:"pagination"`
 }
 
-// get the current followers for the channel and
-func (t *Twitch) getFollowers() error {
+// get the current followers for the channel and save them to the database
+func (t *Twitch) getFollowers(ctx context.Context, channelID string) error {
+	ids := make([]string, 0)
+
+	if err := t.pageFollowers(ctx, channelID, func(f *database.Follower) bool {
+		ids = append(ids, f.FollowerID)
+
+		// add new follower to database, an import of the follower
+		// history rather than events, so the bus is skipped
+		if err := t.database.AddFollower(f); err != nil {
+			log.Printf("unable to add follower [%s] to channel [%s]: %s", f.FollowerID, f.ChannelID, err)
+		}
+
+		return true
+	}); err != nil {
+		return err
+	}
+
+	// cache the follower profiles
+	if err := t.ResolveUsers(ctx, ids); err != nil {
+		log.Printf("[ERROR] followers: channel [%s]: resolve users: %s", channelID, err)
+	}
+
+	return nil
+}
+
+// pages through the current followers for the channel on twitch, newest
+// first, calling fn for each follower until it returns false
+func (t *Twitch) pageFollowers(ctx context.Context, channelID string, fn func(*database.Follower) bool) error {
 	// build query url
-	urlSuffix := strings.Join([]string{TWITCH_HELIX_FOLLOWERS_URL, t.config.TwitchChannelID, "&first=100"}, "")
+	urlSuffix := strings.Join([]string{TWITCH_HELIX_FOLLOWERS_URL, channelID, "&first=100"}, "")
 
 	// track follower count for loop check
 	followerCount := 0
@@ -57,7 +85,7 @@ func (t *Twitch) getFollowers() error {
 		}
 
 		// get followers from twitch
-		body, err := t.getTwitchResponse(TwitchHelix, urlPaginate)
+		body, err := t.getTwitchResponse(ctx, TwitchHelix, urlPaginate)
 		if err != nil {
 			return err
 		}
@@ -73,7 +101,7 @@ func (t *Twitch) getFollowers() error {
 			return fmt.Errorf("no data: %s", string(body))
 		}
 
-		// save follower data to datbase
+		// pass follower data on
 		for _, follower := range followerResp.Data {
 			// parse follow time
 			timestamp, err := time.Parse(time.RFC3339, follower.FollowedAt)
@@ -83,16 +111,12 @@ func (t *Twitch) getFollowers() error {
 			}
 
 			// make new follower
-			f := &database.Follower{
+			if !fn(&database.Follower{
 				ChannelID:  follower.ToID,
 				FollowerID: follower.FromID,
 				Timestamp:  timestamp,
-			}
-
-			// add new follower to database
-			err = t.database.AddFollower(f)
-			if err != nil {
-				log.Printf("unable to add follower [%s] to channel [%s]: %s", f.FollowerID, f.ChannelID, err)
+			}) {
+				return nil
 			}
 		}
 
@@ -103,12 +127,9 @@ func (t *Twitch) getFollowers() error {
 		followerCount += len(followerResp.Data)
 
 		// check for loop end
-		if followerCount >= followerResp.Total {
+		if followerCount >= followerResp.Total || len(cursor) == 0 {
 			loop = false
 		}
-
-		// sleep between api calls
-		time.Sleep(TWITCH_API_DELAY)
 	}
 
 	return nil
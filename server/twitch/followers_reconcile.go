@@ -0,0 +1,118 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+)
+
+var (
+	// how often followers are reconciled against twitch
+	followerReconcilePeriod = 1 * time.Hour
+)
+
+// reconciles followers for every channel on the schedule
+func (t *Twitch) runFollowerReconcile() {
+	ticker := time.NewTicker(followerReconcilePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+			t.reconcileAllFollowers()
+		}
+	}
+}
+
+// reconciles followers for every channel
+func (t *Twitch) reconcileAllFollowers() {
+	for _, channel := range t.config.ChannelList() {
+		if err := t.reconcileFollowers(t.ctx, channel.ID); err != nil {
+			log.Printf("[ERROR] followers: channel [%s]: reconcile: %s", channel.ID, err)
+		}
+	}
+}
+
+// compares the channel followers on twitch with the database, adding
+// followers we missed and marking the ones that left as unfollowed
+func (t *Twitch) reconcileFollowers(ctx context.Context, channelID string) error {
+	log.Printf("[INFO] followers: channel [%s]: reconciling", channelID)
+
+	// get followers from the database first, so a follow saved while
+	// twitch is paged isn't taken for one that left
+	known, err := t.database.GetFollowerIDs(channelID)
+	if err != nil {
+		return err
+	}
+
+	// get current followers from twitch
+	current := make(map[string]*database.Follower)
+	if err := t.pageFollowers(ctx, channelID, func(f *database.Follower) bool {
+		current[f.FollowerID] = f
+		return true
+	}); err != nil {
+		return fmt.Errorf("get followers: %s", err)
+	}
+
+	// leave out rows raids were saved as, raiders never followed
+	raidFollowers, err := t.database.GetRaidFollowerIDs(channelID)
+	if err != nil {
+		return err
+	}
+	for followerID := range raidFollowers {
+		delete(known, followerID)
+	}
+
+	// add followers we missed
+	added := make([]string, 0)
+	for followerID, f := range current {
+		if known[followerID] {
+			continue
+		}
+
+		if err := t.events.Publish(&events.Follow{
+			Meta: events.Meta{
+				Source:    events.SourceReconcile,
+				ChannelID: channelID,
+				Timestamp: f.Timestamp,
+			},
+			UserID: followerID,
+		}); err != nil {
+			if !t.duplicate(EventKindFollow, err) {
+				log.Printf("[ERROR] followers: channel [%s]: add follower [%s]: %s", channelID, followerID, err)
+			}
+			continue
+		}
+		added = append(added, followerID)
+	}
+
+	// cache the new follower profiles
+	if err := t.ResolveUsers(ctx, added); err != nil {
+		log.Printf("[ERROR] followers: channel [%s]: resolve users: %s", channelID, err)
+	}
+
+	// mark followers that left
+	now := time.Now()
+	removed := 0
+	for followerID := range known {
+		if _, ok := current[followerID]; ok {
+			continue
+		}
+
+		if err := t.database.MarkUnfollowed(channelID, followerID, now); err != nil {
+			log.Printf("[ERROR] followers: channel [%s]: mark unfollowed [%s]: %s", channelID, followerID, err)
+			continue
+		}
+		removed++
+	}
+
+	log.Printf("[INFO] followers: channel [%s]: reconciled: %d added, %d unfollowed", channelID, len(added), removed)
+
+	return nil
+}
@@ -1,26 +1,29 @@
 package twitch
 
 import (
-	"context"
 	"fmt"
 	"log"
-	"net/http"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
-
 	"github.com/codephobia/twitch-eos-thanks/server/config"
 	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
 )
 
 var (
 	bearerPrefix   = "Bearer "
-	pubsubURL      = "wss://pubsub-edge.twitch.tv"
 	writeWait      = 1 * time.Second
 	pingPeriod     = 5 * time.Minute
 	pongWait       = 10 * time.Second
-	maxMessageSize = int64(512)
+	maxMessageSize = int64(64 * 1024)
+
+	maxTopicsPerConn = 50
+
+	// connection changes waiting to be handled by the pool
+	pubsubConnChangeSize = 64
 
 	backoffMin    = 1 * time.Second
 	backoffMax    = 2 * time.Minute
@@ -28,63 +31,109 @@ var (
 	backoffJitter = true
 )
 
-// PUBSUB is a pub sub manager for twitch.
+// PUBSUB is a pub sub manager for twitch. Topics are spread across a
+// pool of connections since twitch caps the topics per connection.
 type PUBSUB struct {
 	config   *config.Config
 	database *database.Database
 	twitch   *Twitch
 
-	ctx       context.Context
-	ctxCancel context.CancelFunc
+	mu       sync.Mutex
+	conns    []*PUBSUBConn
+	channels map[string]*PUBSUBChannel
+	pending  map[string]*pubsubPending
 
-	client *http.Client
-	conn   *websocket.Conn
-	Send   chan []byte
+	// frames waiting to be journaled, closed on shutdown. Nothing is
+	// journaled without a writer.
+	journalWriter journalWriter
+	journalMu     sync.RWMutex
+	journalQueue  chan *database.JournalEntry
+	journalClosed bool
 
-	pongTimer *time.Timer
-	pongDone  chan bool
+	// connections lost and restored, handled in the order they happened
+	connChanges chan *pubsubConnChange
+}
 
-	Backoff *Backoff
+// pubsubConnChange is a pool connection going down or coming back.
+type pubsubConnChange struct {
+	conn      *PUBSUBConn
+	restored  bool
+	downSince time.Time
 }
 
-// NewPUBSUB returns a new pub sub.
-func NewPUBSUB(c *config.Config, db *database.Database, t *Twitch) *PUBSUB {
-	ctx, cancel := context.WithCancel(context.Background())
+// PUBSUBConnStatus reports the state of a pool connection and the
+// topics living on it.
+type PUBSUBConnStatus struct {
+	ID          int             `json:"id"`
+	State       PUBSUBConnState `json:"state"`
+	Topics      []string        `json:"topics"`
+	LastMessage *time.Time      `json:"last_message"`
+	Reconnects  int             `json:"reconnects"`
+	NextRetry   *time.Time      `json:"next_retry"`
+}
+
+// NewPUBSUB returns a new pub sub for the given channels.
+func NewPUBSUB(c *config.Config, db *database.Database, t *Twitch, channels []*config.Channel) *PUBSUB {
+	// track state for each channel
+	pubsubChannels := make(map[string]*PUBSUBChannel)
+	for _, channel := range channels {
+		pubsubChannels[channel.ID] = NewPUBSUBChannel(c, channel)
+	}
 
-	return &PUBSUB{
+	p := &PUBSUB{
 		config:   c,
 		database: db,
 		twitch:   t,
 
-		ctx:       ctx,
-		ctxCancel: cancel,
+		channels: pubsubChannels,
+		pending:  make(map[string]*pubsubPending),
 
-		client:   &http.Client{},
-		Send:     make(chan []byte, 256),
-		pongDone: make(chan bool),
+		journalQueue: make(chan *database.JournalEntry, pubsubJournalBuffer),
+		connChanges:  make(chan *pubsubConnChange, pubsubConnChangeSize),
+	}
 
-		Backoff: &Backoff{
-			Min:    backoffMin,
-			Max:    backoffMax,
-			Factor: backoffFactor,
-			Jitter: backoffJitter,
-		},
+	// journal to the database when there is one
+	if db != nil {
+		p.journalWriter = db
 	}
+
+	return p
 }
 
 // Init initializes the pub sub listener.
 func (p *PUBSUB) Init() error {
 	log.Printf("[INFO] pubsub: initializing")
 
-	// connect to twitch pubsub
-	if err := p.connect(); err != nil {
-		return fmt.Errorf("connect: %s", err)
+	// listen again whenever a channel token changes
+	p.twitch.tokens.Subscribe(p.handleTokenChange)
+
+	// journal frames as they're received
+	if p.journalWriter != nil {
+		go p.runJournal()
 	}
 
-	// enable read / write
-	go p.ReadPump()
-	go p.WritePump()
+	// move topics around as connections go down and come back
+	go p.runConnChanges()
+
+	// keep the backfill baselines of listened channels up to date
+	if p.twitch.backfill != nil {
+		go p.runBaselines()
+	}
 
+	// create the connection pool
+	size := p.poolSize()
+	for i := 0; i < size; i++ {
+		p.conns = append(p.conns, NewPUBSUBConn(i, p))
+	}
+
+	// connect to twitch pubsub
+	for _, conn := range p.conns {
+		if err := conn.Start(); err != nil {
+			return fmt.Errorf("connect: %s", err)
+		}
+	}
+
+	// spread the topics over the pool as they're listened for
 	if err := p.listenRequest(); err != nil {
 		return fmt.Errorf("listen request: %s", err)
 	}
@@ -93,207 +142,402 @@ func (p *PUBSUB) Init() error {
 	return nil
 }
 
-// connect to twitch pub sub
-func (p *PUBSUB) connect() error {
-	log.Printf("[INFO] pubsub: connecting")
+// returns the number of connections needed for all channel topics
+func (p *PUBSUB) poolSize() int {
+	// count topics
+	topics := 0
+	for _, channel := range p.channels {
+		topics += len(channel.topics())
+	}
 
-	// create auth headers
-	headers := http.Header{"Authorization": {bearerPrefix + p.config.TwitchOAuthToken}}
+	// round up to fit every topic
+	size := (topics + maxTopicsPerConn - 1) / maxTopicsPerConn
 
-	// dial connection
-	conn, _, err := websocket.DefaultDialer.DialContext(p.ctx, pubsubURL, headers)
-	if err != nil {
-		return fmt.Errorf("unable to dial connection: %s", err)
+	// use configured size if larger
+	if p.config.PubSubConnections > size {
+		size = p.config.PubSubConnections
 	}
 
-	// save connection
-	p.conn = conn
+	if size < 1 {
+		size = 1
+	}
 
-	return nil
+	return size
 }
 
-// sends the listen request to twitch
-func (p *PUBSUB) listenRequest() error {
-	log.Printf("[INFO] pubsub: sending listen request")
+// returns the channels sorted by id, must be called with p.mu held
+func (p *PUBSUB) sortedChannels() []*PUBSUBChannel {
+	channels := make([]*PUBSUBChannel, 0, len(p.channels))
+	for _, channel := range p.channels {
+		channels = append(channels, channel)
+	}
 
-	// create subs listen request
-	subsTopics := []string{
-		strings.Join([]string{PUBSUBTopicSubscription.String(), p.config.TwitchChannelID}, "."),
-		strings.Join([]string{PUBSUBTopicBits.String(), p.config.TwitchChannelID}, "."),
-		strings.Join([]string{PUBSUBTopicCommerce.String(), p.config.TwitchChannelID}, "."),
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].channel.ID < channels[j].channel.ID
+	})
+
+	return channels
+}
+
+// returns the number of topics listened on a connection,
+// must be called with p.mu held
+func (p *PUBSUB) topicCount(conn *PUBSUBConn) int {
+	count := 0
+	for _, channel := range p.channels {
+		count += len(channel.topicsOn(conn))
 	}
-	subsReq := NewPUBSUBRequest(PUBSUBTypeListen.String(), subsTopics, p.config.TwitchChannelOAuthToken)
 
-	// convert request to bytes
-	reqBytes, err := subsReq.ToBytes()
-	if err != nil {
-		return err
+	return count
+}
+
+// returns if the pool has room for more topics, counting connections
+// that are down since their topics wait for them, must be called with
+// p.mu held
+func (p *PUBSUB) hasRoom(topics int) bool {
+	count := 0
+	for _, channel := range p.channels {
+		count += len(channel.activeTopics())
 	}
 
-	// send request
-	p.Send <- reqBytes
+	return count+topics <= len(p.conns)*maxTopicsPerConn
+}
 
-	return nil
+// returns the connected pool connection with the fewest topics that has
+// room for another, skipping the excluded connection, must be called
+// with p.mu held
+func (p *PUBSUB) leastLoaded(exclude *PUBSUBConn) *PUBSUBConn {
+	var least *PUBSUBConn
+	leastCount := 0
+
+	for _, conn := range p.conns {
+		if conn == exclude || !conn.isConnected() {
+			continue
+		}
+
+		count := p.topicCount(conn)
+		if count >= maxTopicsPerConn {
+			continue
+		}
+		if least == nil || count < leastCount {
+			least = conn
+			leastCount = count
+		}
+	}
+
+	return least
 }
 
-// ReadPump reads incoming messages on the websocket connection.
-func (p *PUBSUB) ReadPump() {
-	defer func() {
-		log.Printf("[INFO] pubsub: closing read")
-		p.conn.Close()
-	}()
+// returns the connected pool connection with the most topics, skipping
+// the excluded connection, must be called with p.mu held
+func (p *PUBSUB) mostLoaded(exclude *PUBSUBConn) *PUBSUBConn {
+	var most *PUBSUBConn
+	mostCount := 0
 
-	p.conn.SetReadLimit(maxMessageSize)
-	p.conn.SetReadDeadline(time.Now().Add(pongWait + pingPeriod))
-	p.conn.SetPongHandler(func(string) error { p.conn.SetReadDeadline(time.Now().Add(pongWait + pingPeriod)); return nil })
+	for _, conn := range p.conns {
+		if conn == exclude || !conn.isConnected() {
+			continue
+		}
 
-	for {
-		_, message, err := p.conn.ReadMessage()
-		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-				log.Printf("[ERROR] unexpected close error: %s", err)
-				return
-			}
-			break
+		count := p.topicCount(conn)
+		if most == nil || count > mostCount {
+			most = conn
+			mostCount = count
 		}
+	}
 
-		// convert bytes to message
-		msg, err := NewPUBSUBMessage(message)
-		if err != nil {
-			log.Printf("[ERROR] pub sub message: %s", err)
+	return most
+}
+
+// puts the channel topics that aren't on a connection yet on the least
+// loaded connection with room, returning the topics placed, must be
+// called with p.mu held
+func (p *PUBSUB) placeTopics(channel *PUBSUBChannel) []string {
+	placed := make([]string, 0)
+	for _, topic := range channel.activeTopics() {
+		if channel.conns[topic] != nil {
 			continue
 		}
 
-		log.Printf("[INFO] message: %+v", msg)
+		conn := p.leastLoaded(nil)
+		if conn == nil {
+			log.Printf("[INFO] pubsub: channel [%s]: no connection with room for topic [%s]", channel.channel.ID, topic)
+			continue
+		}
 
-		// handle message
-		p.handleWSMessage(msg)
+		channel.conns[topic] = conn
+		placed = append(placed, topic)
 	}
+
+	return placed
 }
 
-// WritePump writes outgoing messages on the websocket connection.
-func (p *PUBSUB) WritePump() {
-	// TODO: add jitter to ping timer
-	ticker := time.NewTicker(pingPeriod)
-	defer func() {
-		log.Printf("[INFO] pubsub: closing write")
-		ticker.Stop()
-		p.conn.Close()
-	}()
+// Connections reports the state of each pool connection
+// and which topics live on it.
+func (p *PUBSUB) Connections() []*PUBSUBConnStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]*PUBSUBConnStatus, 0, len(p.conns))
+	for _, conn := range p.conns {
+		status := conn.Status()
+		status.Topics = make([]string, 0)
+
+		for _, channel := range p.sortedChannels() {
+			status.Topics = append(status.Topics, channel.topicsOn(conn)...)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
 
+// handles connection changes one at a time, so a connection that's
+// restored is never handled before it was lost
+func (p *PUBSUB) runConnChanges() {
 	for {
 		select {
-		case <-p.ctx.Done():
+		case <-p.twitch.ctx.Done():
 			return
-		case <-ticker.C:
-			p.conn.SetWriteDeadline(time.Now().Add(writeWait))
-
-			// send ws ping
-			if err := p.conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
-				log.Printf("[ERROR] sending ping: %s", err)
-				return
+		case change := <-p.connChanges:
+			if change.restored {
+				p.connRestored(change.conn)
+			} else {
+				p.connLost(change.conn, change.downSince)
 			}
+		}
+	}
+}
 
-			// ping twitch
-			p.pingTwitch()
-		case message, ok := <-p.Send:
-			p.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// close connection
-				p.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
+// sends a connection change to the pool, unless twitch is closed and
+// nothing is left to handle it
+func (p *PUBSUB) connChanged(change *pubsubConnChange) {
+	select {
+	case p.connChanges <- change:
+	case <-p.twitch.ctx.Done():
+	}
+}
+
+// moves the topics from a lost connection to the rest of the pool,
+// noting when their channels went down so they're backfilled once
+// listened to
+func (p *PUBSUB) connLost(conn *PUBSUBConn, downSince time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// requests on the lost connection will never be answered
+	p.dropPending(conn)
+
+	for _, channel := range p.sortedChannels() {
+		lost := channel.topicsOn(conn)
+		if len(lost) == 0 {
+			continue
+		}
 
-			w, err := p.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
+		// keep the start of a gap across repeated reconnects
+		if channel.downSince.IsZero() {
+			channel.downSince = downSince
+		}
+
+		// move each topic to a connection with room for it
+		moved := make([]string, 0, len(lost))
+		for _, topic := range lost {
+			target := p.leastLoaded(conn)
+			if target == nil {
+				log.Printf("[INFO] pubsub: channel [%s]: topic [%s]: waiting for conn [%d] to reconnect", channel.channel.ID, topic, conn.id)
+				continue
 			}
 
-			w.Write(message)
+			log.Printf("[INFO] pubsub: channel [%s]: topic [%s]: moving from conn [%d] to conn [%d]", channel.channel.ID, topic, conn.id, target.id)
+			channel.conns[topic] = target
+			moved = append(moved, topic)
+		}
+
+		// listen on the new connections
+		if err := p.sendRequest(channel, PUBSUBTypeListen, moved); err != nil {
+			log.Printf("[ERROR] pubsub: channel [%s]: listen request: %s", channel.channel.ID, err)
+		}
+	}
+}
+
+// sends the listen requests for the topics left on a restored
+// connection and any that had no room anywhere, then moves topics over
+// to it from busier connections
+func (p *PUBSUB) connRestored(conn *PUBSUBConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, channel := range p.sortedChannels() {
+		topics := append(channel.topicsOn(conn), p.placeTopics(channel)...)
+		if err := p.sendRequest(channel, PUBSUBTypeListen, topics); err != nil {
+			log.Printf("[ERROR] pubsub: channel [%s]: listen request: %s", channel.channel.ID, err)
+		}
+	}
+
+	p.rebalance(conn)
+}
+
+// evens out the pool by moving topics from the busiest connections on
+// to one with fewer, must be called with p.mu held
+func (p *PUBSUB) rebalance(conn *PUBSUBConn) {
+	for {
+		busiest := p.mostLoaded(conn)
+		if busiest == nil || p.topicCount(busiest)-p.topicCount(conn) <= 1 {
+			return
+		}
 
-			if err := w.Close(); err != nil {
-				return
+		// take the last topic on the busiest connection
+		var channel *PUBSUBChannel
+		topic := ""
+		for _, c := range p.sortedChannels() {
+			if topics := c.topicsOn(busiest); len(topics) > 0 {
+				channel = c
+				topic = topics[len(topics)-1]
 			}
 		}
+
+		log.Printf("[INFO] pubsub: channel [%s]: topic [%s]: moving from conn [%d] to conn [%d]", channel.channel.ID, topic, busiest.id, conn.id)
+
+		// listen on the new connection before leaving the old one,
+		// anything delivered on both is dropped as a duplicate
+		channel.conns[topic] = conn
+		if err := p.sendRequest(channel, PUBSUBTypeListen, []string{topic}); err != nil {
+			log.Printf("[ERROR] pubsub: channel [%s]: listen request: %s", channel.channel.ID, err)
+		}
+		if err := p.sendConnRequest(channel, busiest, PUBSUBTypeUnListen, []string{topic}); err != nil {
+			log.Printf("[ERROR] pubsub: channel [%s]: unlisten request: %s", channel.channel.ID, err)
+		}
+	}
+}
+
+// sends the listen requests for every channel to twitch
+func (p *PUBSUB) listenRequest() error {
+	log.Printf("[INFO] pubsub: sending listen requests")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, channel := range p.sortedChannels() {
+		if err := p.listenChannel(channel); err != nil {
+			return fmt.Errorf("channel [%s]: %s", channel.channel.ID, err)
+		}
 	}
+
+	return nil
+}
+
+// sends the listen requests for a single channel on the connections of
+// its topics, placing any that aren't on one yet, must be called with
+// p.mu held
+func (p *PUBSUB) listenChannel(channel *PUBSUBChannel) error {
+	p.placeTopics(channel)
+
+	return p.sendRequest(channel, PUBSUBTypeListen, channel.activeTopics())
 }
 
 // handle incoming websocket messages
-func (p *PUBSUB) handleWSMessage(msg *PUBSUBMessage) {
+func (p *PUBSUB) handleWSMessage(conn *PUBSUBConn, msg *PUBSUBMessage) {
 	switch msg.Type {
 	case PUBSUBTypeResponse:
-		// return out if empty error
-		if len(msg.Error) == 0 {
-			return
-		}
-
-		// handle error
-		p.handleResponseError(msg.Error)
+		p.handleResponse(msg.Nonce, msg.Error)
 	case PUBSUBTypeMessage:
 		log.Printf("[INFO] pubsub: message: %+v", msg.Data)
-		p.handleMessage(msg)
-	case PUBSUBTypePong:
-		log.Printf("[INFO] pubsub: PONG")
-		p.pongDone <- true
-	case PUBSUBTypeReconnect:
-		log.Printf("[INFO] pubsub: reconnect alert received")
-		p.reconnect()
+
+		// keep anything that couldn't be decoded or saved
+		if err := p.handleMessage(msg.Data.Topic, msg.Data.Message); err != nil {
+			log.Printf("[ERROR] pubsub: topic [%s]: %s", msg.Data.Topic, err)
+			p.twitch.deadLetter(&database.DeadLetter{
+				Source:  database.DeadLetterSourcePubSub,
+				Topic:   msg.Data.Topic,
+				Payload: msg.Data.Message,
+			}, err)
+		}
 	}
 }
 
-func (p *PUBSUB) handleResponseError(err string) {
-	switch PUBSUBMessageError(err) {
-	// bad auth token
-	case errBadAuth:
-		log.Printf("[ERROR] pubsub: response: bad auth: %s", err)
+// refreshes a channel token after bad auth and sends its listen
+// request again, leaving every other channel on the connection alone
+func (p *PUBSUB) relistenChannel(channel *PUBSUBChannel) {
+	channelID := channel.channel.ID
 
-		// refresh oauth token
-		if err := p.refreshToken(); err != nil {
-			log.Printf("[ERROR] pubsub: %s", err)
-			return
-		}
+	p.mu.Lock()
+
+	// skip if a refresh for the channel is already running
+	if channel.refreshing {
+		p.mu.Unlock()
+		return
+	}
+
+	// give up on the channel if refreshing isn't fixing auth
+	channel.badAuth++
+	if channel.badAuth > maxChannelBadAuth {
+		p.mu.Unlock()
+		log.Printf("[ERROR] pubsub: channel [%s]: giving up after %d bad auth responses", channelID, maxChannelBadAuth)
+		return
+	}
+
+	channel.refreshing = true
+	p.mu.Unlock()
 
-		// reconnect to twitch websocket
-		p.reconnect()
-	case errBadMessage:
-		log.Printf("[ERROR] pubsub: response: bad message: %s", err)
-	case errBadTopic:
-		log.Printf("[ERROR] pubsub: response: bad topic: %s", err)
-	case errServer2:
-		fallthrough
-	case errServer:
-		log.Printf("[ERROR] pubsub: response: server: %s", err)
+	// refresh oauth token, the token change listens again
+	err := p.twitch.tokens.Refresh(channelID)
+
+	p.mu.Lock()
+	channel.refreshing = false
+	p.mu.Unlock()
+
+	if err != nil {
+		log.Printf("[ERROR] pubsub: channel [%s]: %s", channelID, err)
 	}
 }
 
-// handle a websocket message of type MESSAGE
-func (p *PUBSUB) handleMessage(msg *PUBSUBMessage) {
+// sends the listen request again for a channel whose token changed
+func (p *PUBSUB) handleTokenChange(change *TokenChange) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// skip channels that aren't on pub sub
+	channel, ok := p.channels[change.ChannelID]
+	if !ok {
+		return
+	}
+
+	// a new token may fix topics given up on
+	channel.resetTopics()
+
+	// send listen request with the new token
+	if err := p.listenChannel(channel); err != nil {
+		log.Printf("[ERROR] pubsub: channel [%s]: listen request: %s", change.ChannelID, err)
+	}
+}
+
+// handle the message of a websocket message of type MESSAGE, publishing
+// its event and returning an error if it couldn't be decoded or saved
+func (p *PUBSUB) handleMessage(msgTopic string, message string) error {
 	// split topic from channel id
-	msgTopic := strings.Split(msg.Data.Topic, ".")
+	topicParts := strings.Split(msgTopic, ".")
 
 	// validate topic split length
-	if len(msgTopic) != 2 {
-		log.Printf("invalid message topic: %+v", msgTopic)
-		return
+	if len(topicParts) != 2 {
+		return fmt.Errorf("invalid message topic: %s", msgTopic)
 	}
 
-	topic := msgTopic[0]
-	channelID := msgTopic[1]
+	topic := topicParts[0]
+	channelID := topicParts[1]
 
 	switch PUBSUBTopic(topic) {
 	case PUBSUBTopicSubscription:
-		subscription, err := NewPUBSUBSubscriptionMessage(msg.Data.Message)
+		subscription, err := NewPUBSUBSubscriptionMessage(message)
 		if err != nil {
-			log.Printf("[ERROR] sub message: %s", err)
-			return
+			return fmt.Errorf("sub message: %s", err)
 		}
 
-		// generate emotes for database
-		messageEmotes := make([]*database.SubMessageEmote, 0)
+		// generate emotes for the event
+		messageEmotes := make([]*events.Emote, 0)
 
 		// loop through sub emotes
 		for _, emote := range subscription.SubMessage.Emotes {
-			messageEmotes = append(messageEmotes, &database.SubMessageEmote{
+			messageEmotes = append(messageEmotes, &events.Emote{
 				Start: emote.Start,
 				End:   emote.End,
 				ID:    emote.ID,
@@ -307,32 +551,62 @@ func (p *PUBSUB) handleMessage(msg *PUBSUBMessage) {
 			timestamp = time.Now()
 		}
 
-		// add the subscriber to the database
-		if err := p.database.AddSubscriber(&database.Subscriber{
-			ChannelID:    channelID,
-			SubscriberID: subscription.UserID,
-			Timestamp:    timestamp,
-
-			DisplayName: subscription.DisplayName,
-			SubPlan:     subscription.SubPlan,
-			SubPlanName: subscription.SubPlanName,
-			Months:      subscription.Months,
-			Context:     subscription.Context,
-			SubMessage: &database.SubMessage{
-				Message: subscription.SubMessage.Message,
-				Emotes:  messageEmotes,
+		// prefer cumulative months over the deprecated months field
+		if subscription.Cumulative > subscription.Months {
+			subscription.Months = subscription.Cumulative
+		}
+
+		// build the subscription event
+		sub := &events.Subscription{
+			Meta: events.Meta{
+				MessageID: pubsubMessageID(msgTopic, message, ""),
+				Source:    events.SourcePubSub,
+				ChannelID: channelID,
+				Timestamp: timestamp,
 			},
-		}); err != nil {
-			log.Printf("[ERROR] add sub: %s", err)
+			UserID:   subscription.UserID,
+			UserName: subscription.DisplayName,
+			Tier:     subscription.SubPlan,
+			PlanName: subscription.SubPlanName,
+			Months:   subscription.Months,
+			Streak:   subscription.Streak,
+			Context:  subscription.Context,
+			Message: &events.Message{
+				Text:   subscription.SubMessage.Message,
+				Emotes: messageEmotes,
+			},
+
+			MultiMonthDuration: subscription.MultiMonthDuration,
 		}
 
-		return
+		// gifted subs belong to the recipient, crediting the gifter
+		if subscription.IsGift || len(subscription.RecipientID) > 0 {
+			sub.IsGift = true
+			sub.IsAnonymous = subscription.Context == "anonsubgift"
+			sub.GifterID = subscription.UserID
+			sub.GifterName = subscription.DisplayName
+			sub.UserID = subscription.RecipientID
+			sub.UserName = subscription.RecipientDisplayName
+
+			if sub.IsAnonymous {
+				sub.GifterName = "Anonymous"
+			}
+		}
+
+		// publish the subscription
+		if err := p.twitch.events.Publish(sub); err != nil && !p.twitch.duplicate(EventKindSubscription, err) {
+			return fmt.Errorf("add sub: %s", err)
+		}
+
+		// cache the subscriber profile
+		p.twitch.ResolveUsersAsync(sub.UserID)
+
+		return nil
 	case PUBSUBTopicBits:
 		// convert message string to bits message
-		bits, err := NewPUBSUBBitsMessage(msg.Data.Message)
+		bits, err := NewPUBSUBBitsMessage(message)
 		if err != nil {
-			log.Printf("[ERROR] bits message: %s", err)
-			return
+			return fmt.Errorf("bits message: %s", err)
 		}
 
 		// convert timestamp
@@ -342,122 +616,130 @@ func (p *PUBSUB) handleMessage(msg *PUBSUBMessage) {
 			timestamp = time.Now()
 		}
 
-		// create badge entitlement
-		badgeEntitlement := &database.BadgeEntitlement{}
-		if bits.Data.BadgeEntitlement != nil {
-			badgeEntitlement.NewVersion = bits.Data.BadgeEntitlement.NewVersion
-			badgeEntitlement.PreviousVersion = bits.Data.BadgeEntitlement.PreviousVersion
-		}
-
-		// add the bit event to the database
-		if err := p.database.AddBit(&database.Bit{
-			UserName:         bits.Data.UserName,
-			ChannelName:      bits.Data.ChannelName,
-			UserID:           bits.Data.UserID,
-			ChannelID:        bits.Data.ChannelID,
-			Time:             timestamp,
-			ChatMessage:      bits.Data.ChatMessage,
-			BitsUsed:         bits.Data.BitsUsed,
-			TotalBitsUsed:    bits.Data.TotalBitsUsed,
-			Context:          bits.Data.Context,
-			BadgeEntitlement: badgeEntitlement,
-		}); err != nil {
-			log.Printf("[ERROR] add bits: %s", err)
+		cheer := &events.Cheer{
+			Meta: events.Meta{
+				MessageID: pubsubMessageID(msgTopic, message, bits.MessageID),
+				Source:    events.SourcePubSub,
+				ChannelID: bits.Data.ChannelID,
+				Timestamp: timestamp,
+			},
+			UserID:      bits.Data.UserID,
+			UserName:    bits.Data.UserName,
+			ChannelName: bits.Data.ChannelName,
+			Message:     bits.Data.ChatMessage,
+			Bits:        bits.Data.BitsUsed,
+			TotalBits:   bits.Data.TotalBitsUsed,
+			Context:     bits.Data.Context,
 		}
 
-		return
-	case PUBSUBTopicCommerce:
-		log.Printf("commerce: %s", msg.Data.Message)
-		return
-	case PUBSUBTopicWhispers:
-		log.Printf("whispers: %s", msg.Data.Message)
-		return
-	}
-}
+		// add badge entitlement
+		if bits.Data.BadgeEntitlement != nil {
+			cheer.BadgeVersion = bits.Data.BadgeEntitlement.NewVersion
+			cheer.PreviousBadgeVersion = bits.Data.BadgeEntitlement.PreviousVersion
+		}
 
-// sends a ping to twitch over the websocket
-func (p *PUBSUB) pingTwitch() {
-	// send twitch ping
-	ping := &PUBSUBMessage{
-		Type: PUBSUBTypePing,
-	}
+		// publish the cheer
+		if err := p.twitch.events.Publish(cheer); err != nil && !p.twitch.duplicate(EventKindBits, err) {
+			return fmt.Errorf("add bits: %s", err)
+		}
 
-	// convert ping to bytes
-	pingBytes, err := ping.ToBytes()
-	if err != nil {
-		log.Printf("[ERROR] unable to generate ping: %s", err)
-		return
-	}
+		// cache the cheerer profile
+		p.twitch.ResolveUsersAsync(bits.Data.UserID)
 
-	// send ping
-	p.Send <- pingBytes
+		return nil
+	case PUBSUBTopicCommerce:
+		// convert message string to commerce message
+		commerce, err := NewPUBSUBCommerceMessage(message)
+		if err != nil {
+			return fmt.Errorf("commerce message: %s", err)
+		}
 
-	// start pong check
-	p.pongCheck()
-}
+		// generate emotes for the event
+		messageEmotes := make([]*events.Emote, 0)
 
-// checks if we receive a pong within 10 seconds
-// otherwise reconnect the websocket connection
-func (p *PUBSUB) pongCheck() {
-	// setup pong checker
-	p.pongTimer = time.NewTimer(pongWait)
+		// loop through purchase emotes
+		for _, emote := range commerce.PurchaseMessage.Emotes {
+			messageEmotes = append(messageEmotes, &events.Emote{
+				Start: emote.Start,
+				End:   emote.End,
+				ID:    emote.ID,
+			})
+		}
 
-	go func() {
-		select {
-		case <-p.pongTimer.C:
-			// pong timer lapsed so now we reconnect
-			log.Printf("[INFO] PONG timeout: reconnecting")
-			p.reconnect()
-		case <-p.ctx.Done():
-		case <-p.pongDone:
-			// pong received so stop timer
-			log.Printf("[INFO] PONG received in time")
-			p.pongTimer.Stop()
-		}
-	}()
-}
+		// convert timestamp
+		timestamp, err := time.Parse(time.RFC3339, commerce.Time)
+		if err != nil {
+			log.Printf("[ERROR] unable to convert commerce timestamp: %s", err)
+			timestamp = time.Now()
+		}
 
-// reconnects an existing connection to twitch pub sub
-func (p *PUBSUB) reconnect() {
-	log.Printf("[INFO] pubsub: reconnect")
+		// publish the purchase
+		if err := p.twitch.events.Publish(&events.Purchase{
+			Meta: events.Meta{
+				MessageID: pubsubMessageID(msgTopic, message, ""),
+				Source:    events.SourcePubSub,
+				ChannelID: channelID,
+				Timestamp: timestamp,
+			},
+			UserID:          commerce.UserID,
+			UserName:        commerce.UserName,
+			DisplayName:     commerce.DisplayName,
+			ChannelName:     commerce.ChannelName,
+			ItemImageURL:    commerce.ItemImageURL,
+			ItemDescription: commerce.ItemDescription,
+			SupportsChannel: commerce.SupportsChannel,
+			Message: &events.Message{
+				Text:   commerce.PurchaseMessage.Message,
+				Emotes: messageEmotes,
+			},
+		}); err != nil && !p.twitch.duplicate(EventKindCommerce, err) {
+			return fmt.Errorf("add commerce: %s", err)
+		}
 
-	// cancel current connection context
-	p.ctxCancel()
+		return nil
+	case PUBSUBTopicRedemption:
+		// convert message string to redemption message
+		redemption, err := NewPUBSUBRedemptionMessage(message)
+		if err != nil {
+			return fmt.Errorf("redemption message: %s", err)
+		}
 
-	// update context
-	p.ctx, p.ctxCancel = context.WithCancel(context.Background())
+		// only store redeemed rewards
+		if redemption.Type != "reward-redeemed" || redemption.Data == nil {
+			return nil
+		}
+		r := redemption.Data.Redemption
 
-	// create backoff timer
-	timer := time.NewTimer(p.Backoff.Duration())
+		// convert timestamp
+		timestamp, err := time.Parse(time.RFC3339, r.RedeemedAt)
+		if err != nil {
+			log.Printf("[ERROR] unable to convert redemption timestamp: %s", err)
+			timestamp = time.Now()
+		}
 
-	// wait for timer before attempting reconnect
-	go func(timer *time.Timer) {
-		select {
-		case <-timer.C:
-			p.attemptReconnect()
+		// publish the redemption
+		if err := p.twitch.events.Publish(&events.Redemption{
+			Meta: events.Meta{
+				MessageID: pubsubMessageID(msgTopic, message, r.ID),
+				Source:    events.SourcePubSub,
+				ChannelID: channelID,
+				Timestamp: timestamp,
+			},
+			RedemptionID: r.ID,
+			UserID:       r.User.ID,
+			UserName:     r.User.Login,
+			DisplayName:  r.User.DisplayName,
+			RewardID:     r.Reward.ID,
+			RewardTitle:  r.Reward.Title,
+			RewardCost:   r.Reward.Cost,
+			UserInput:    r.UserInput,
+			Status:       r.Status,
+		}); err != nil && !p.twitch.duplicate(EventKindRedemption, err) {
+			return fmt.Errorf("add redemption: %s", err)
 		}
-	}(timer)
-}
 
-func (p *PUBSUB) attemptReconnect() {
-	// connect to twitch
-	if err := p.connect(); err != nil {
-		log.Printf("[ERROR] connect: %s", err)
-		p.reconnect()
-		return
+		return nil
 	}
 
-	// reset backoff
-	p.Backoff.Reset()
-
-	// enable read / write
-	go p.ReadPump()
-	go p.WritePump()
-
-	// send listen request
-	if err := p.listenRequest(); err != nil {
-		// TODO: gracefully fail, and re-attempt
-		log.Printf("[ERROR] listen request: %s", err)
-		return
-	}
+	return nil
 }
@@ -0,0 +1,53 @@
+package twitch
+
+import (
+	"time"
+)
+
+var (
+	// how often the channels listened to are recorded, the start of
+	// the gap backfilled after a restart
+	baselineHeartbeatPeriod = 1 * time.Minute
+	// how often the channels listened to retake their baseline
+	baselineRefreshPeriod = 6 * time.Hour
+)
+
+// keeps the backfill baselines of listened channels up to date until
+// twitch is closed
+func (p *PUBSUB) runBaselines() {
+	heartbeat := time.NewTicker(baselineHeartbeatPeriod)
+	defer heartbeat.Stop()
+
+	refresh := time.NewTicker(baselineRefreshPeriod)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-p.twitch.ctx.Done():
+			// the channels are listened to up to shutdown
+			p.twitch.backfill.Listened(p.listenedChannels(), time.Now())
+			return
+		case <-heartbeat.C:
+			p.twitch.backfill.Listened(p.listenedChannels(), time.Now())
+		case <-refresh.C:
+			for _, channelID := range p.listenedChannels() {
+				p.twitch.backfill.Refresh(channelID)
+			}
+		}
+	}
+}
+
+// returns the ids of the baselined channels that are listened to
+func (p *PUBSUB) listenedChannels() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channelIDs := make([]string, 0)
+	for channelID, channel := range p.channels {
+		if channel.baselined && !channel.baselining && channel.downSince.IsZero() {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+
+	return channelIDs
+}
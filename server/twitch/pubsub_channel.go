@@ -0,0 +1,168 @@
+package twitch
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/config"
+)
+
+var (
+	maxChannelBadAuth = 3
+	maxTopicFailures  = 3
+)
+
+// PUBSUBChannel tracks the listen state of a single channel on pub sub.
+type PUBSUBChannel struct {
+	config  *config.Config
+	channel *config.Channel
+
+	// conns holds the pool connection each topic is listened on, topics
+	// are spread across the pool on their own
+	conns map[string]*PUBSUBConn
+
+	// refreshing is set while the channel token is being refreshed
+	refreshing bool
+	// badAuth counts ERR_BADAUTH responses since the last good listen
+	badAuth int
+
+	// failures counts failed listens per topic since its last good listen
+	failures map[string]int
+	// broken holds the last error of topics given up on
+	broken map[string]string
+
+	// downSince is when the channel stopped being listened to, until
+	// it's listened to again
+	downSince time.Time
+	// baselined is set once the channel has a whole backfill baseline
+	baselined bool
+	// baselining is set while the baseline is being taken
+	baselining bool
+}
+
+// NewPUBSUBChannel returns a new pub sub channel, reporting any topics
+// in the config that pub sub can't listen for.
+func NewPUBSUBChannel(cfg *config.Config, c *config.Channel) *PUBSUBChannel {
+	channel := &PUBSUBChannel{
+		config:  cfg,
+		channel: c,
+
+		conns:    make(map[string]*PUBSUBConn),
+		failures: make(map[string]int),
+		broken:   make(map[string]string),
+	}
+
+	for _, name := range channel.topicNames() {
+		if !validTopic(PUBSUBTopic(name)) {
+			log.Printf("[ERROR] pubsub: channel [%s]: skipping unsupported topic %s", c.ID, name)
+		}
+	}
+
+	return channel
+}
+
+// topics returns the pub sub topics for the channel, skipping any in
+// the config that pub sub can't listen for.
+func (c *PUBSUBChannel) topics() []string {
+	topics := make([]string, 0)
+	for _, name := range c.topicNames() {
+		if !validTopic(PUBSUBTopic(name)) {
+			continue
+		}
+
+		topics = append(topics, c.topicName(PUBSUBTopic(name)))
+	}
+
+	return topics
+}
+
+// topicNames returns the names of the topics chosen for the channel,
+// or the default topics if it hasn't chosen any.
+func (c *PUBSUBChannel) topicNames() []string {
+	if topics := c.config.ChannelTopics(c.channel); topics != nil {
+		return topics
+	}
+
+	names := make([]string, 0)
+	for _, topic := range pubsubDefaultTopics {
+		names = append(names, topic.String())
+	}
+
+	return names
+}
+
+// topicName returns the full name of a topic for the channel.
+func (c *PUBSUBChannel) topicName(topic PUBSUBTopic) string {
+	return strings.Join([]string{topic.String(), c.channel.ID}, ".")
+}
+
+// activeTopics returns the topics to listen for, leaving out broken ones.
+func (c *PUBSUBChannel) activeTopics() []string {
+	topics := make([]string, 0)
+	for _, topic := range c.topics() {
+		if !c.topicBroken(topic) {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics
+}
+
+// topicsOn returns the active topics listened on a connection.
+func (c *PUBSUBChannel) topicsOn(conn *PUBSUBConn) []string {
+	topics := make([]string, 0)
+	for _, topic := range c.activeTopics() {
+		if c.conns[topic] == conn {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics
+}
+
+// hasTopic returns if the topic belongs to the channel.
+func (c *PUBSUBChannel) hasTopic(topic string) bool {
+	for _, t := range c.topics() {
+		if t == topic {
+			return true
+		}
+	}
+
+	return false
+}
+
+// topicListened clears the failures of a topic after a good listen.
+func (c *PUBSUBChannel) topicListened(topic string) {
+	delete(c.failures, topic)
+}
+
+// topicFailed counts a failed listen for a topic, marking it broken once
+// it fails too many times, and returns its failure count.
+func (c *PUBSUBChannel) topicFailed(topic string, err string) int {
+	c.failures[topic]++
+	if c.failures[topic] >= maxTopicFailures {
+		c.broken[topic] = err
+	}
+
+	return c.failures[topic]
+}
+
+// topicBroken returns if a topic has been given up on.
+func (c *PUBSUBChannel) topicBroken(topic string) bool {
+	_, ok := c.broken[topic]
+	return ok
+}
+
+// forgetTopic clears the connection and failures of a removed topic.
+func (c *PUBSUBChannel) forgetTopic(topic string) {
+	delete(c.conns, topic)
+	delete(c.failures, topic)
+	delete(c.broken, topic)
+}
+
+// resetTopics gives every topic a fresh start.
+func (c *PUBSUBChannel) resetTopics() {
+	c.failures = make(map[string]int)
+	c.broken = make(map[string]string)
+}
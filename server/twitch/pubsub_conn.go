@@ -0,0 +1,432 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/codephobia/twitch-eos-thanks/backoff"
+)
+
+var (
+	pubsubDialTimeout = 10 * time.Second
+	pubsubEventSize   = 64
+)
+
+// PUBSUBConn is a single websocket connection in the pub sub pool. The
+// connection is a state machine driven by its run goroutine, which makes
+// every change to it. Other goroutines only queue requests on Send and
+// read the published status.
+type PUBSUBConn struct {
+	id   int
+	pool *PUBSUB
+
+	Send   chan []byte
+	events chan *pubsubConnEvent
+
+	// owned by the run goroutine
+	ws           *websocket.Conn
+	state        PUBSUBConnState
+	pingTimer    *time.Timer
+	pongTimer    *time.Timer
+	retryTimer   *time.Timer
+	pongMissed   bool
+	listenFailed bool
+	lastMessage  time.Time
+	reconnects   int
+	nextRetry    time.Time
+	downSince    time.Time
+
+	// status published by the run goroutine
+	mu     sync.Mutex
+	status PUBSUBConnStatus
+
+	Backoff *backoff.Backoff
+}
+
+// pubsubConnEvent is something that happened on a websocket, handed
+// from its read goroutine to the run goroutine.
+type pubsubConnEvent struct {
+	ws  *websocket.Conn
+	msg *PUBSUBMessage
+	err error
+}
+
+// NewPUBSUBConn returns a new pub sub connection for the pool.
+func NewPUBSUBConn(id int, pool *PUBSUB) *PUBSUBConn {
+	return &PUBSUBConn{
+		id:   id,
+		pool: pool,
+
+		Send:   make(chan []byte, 256),
+		events: make(chan *pubsubConnEvent, pubsubEventSize),
+
+		state: PUBSUBConnStateConnecting,
+		status: PUBSUBConnStatus{
+			ID:    id,
+			State: PUBSUBConnStateConnecting,
+		},
+
+		Backoff: &backoff.Backoff{
+			Min:    backoffMin,
+			Max:    backoffMax,
+			Factor: backoffFactor,
+			Jitter: backoffJitter,
+		},
+	}
+}
+
+// Start dials twitch pub sub, then hands the connection over to its
+// run goroutine.
+func (c *PUBSUBConn) Start() error {
+	ws, err := c.dial()
+	if err != nil {
+		return err
+	}
+
+	// nothing else touches the connection until run starts
+	c.connected(ws)
+	go c.run()
+
+	return nil
+}
+
+// Status returns the published status of the connection, without topics.
+func (c *PUBSUBConn) Status() *PUBSUBConnStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := c.status
+	return &status
+}
+
+// isConnected returns if the connection is currently up.
+func (c *PUBSUBConn) isConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.status.State == PUBSUBConnStateListening || c.status.State == PUBSUBConnStateDegraded
+}
+
+// handles connection events, timers and outgoing requests until twitch
+// is closed
+func (c *PUBSUBConn) run() {
+	for {
+		select {
+		case <-c.pool.twitch.ctx.Done():
+			c.close()
+			return
+		case ev := <-c.events:
+			c.handleEvent(ev)
+		case message := <-c.Send:
+			c.write(message)
+		case <-timerC(c.pingTimer):
+			c.pingTimer = nil
+			c.ping()
+		case <-timerC(c.pongTimer):
+			c.pongTimer = nil
+			c.pongTimeout()
+		case <-timerC(c.retryTimer):
+			c.retryTimer = nil
+			c.retry()
+		}
+	}
+}
+
+// dial a connection to twitch pub sub
+func (c *PUBSUBConn) dial() (*websocket.Conn, error) {
+	log.Printf("[INFO] pubsub: conn [%d]: connecting", c.id)
+
+	// create auth headers
+	headers := http.Header{"Authorization": {bearerPrefix + c.pool.config.TwitchOAuthToken}}
+
+	ctx, cancel := context.WithTimeout(c.pool.twitch.ctx, pubsubDialTimeout)
+	defer cancel()
+
+	// dial connection
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, c.pool.config.PubSubURL(), headers)
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial connection: %s", err)
+	}
+
+	return ws, nil
+}
+
+// moves to listening on a newly dialed websocket
+func (c *PUBSUBConn) connected(ws *websocket.Conn) {
+	c.ws = ws
+	c.pongMissed = false
+	c.listenFailed = false
+	c.nextRetry = time.Time{}
+	c.downSince = time.Time{}
+	c.Backoff.Reset()
+
+	// TODO: add jitter to ping timer
+	c.pingTimer = time.NewTimer(pingPeriod)
+
+	c.setState(PUBSUBConnStateListening)
+
+	// enable read
+	go c.readPump(ws)
+}
+
+// readPump reads incoming messages on a websocket, handing them to the
+// pool and the run goroutine. Only the run goroutine closes the websocket.
+func (c *PUBSUBConn) readPump(ws *websocket.Conn) {
+	defer log.Printf("[INFO] pubsub: conn [%d]: closing read", c.id)
+
+	ws.SetReadLimit(maxMessageSize)
+	ws.SetReadDeadline(time.Now().Add(pongWait + pingPeriod))
+	ws.SetPongHandler(func(string) error { ws.SetReadDeadline(time.Now().Add(pongWait + pingPeriod)); return nil })
+
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			c.sendEvent(&pubsubConnEvent{ws: ws, err: err})
+			return
+		}
+
+		// keep the raw frame so it can be replayed
+		c.pool.journal(c.id, message)
+
+		// convert bytes to message
+		msg, err := NewPUBSUBMessage(message)
+		if err != nil {
+			log.Printf("[ERROR] pub sub message: %s", err)
+			continue
+		}
+
+		// topic messages and listen responses are handled by the pool
+		c.pool.handleWSMessage(c, msg)
+
+		c.sendEvent(&pubsubConnEvent{ws: ws, msg: msg})
+	}
+}
+
+// hands an event to the run goroutine, unless it stopped on shutdown
+func (c *PUBSUBConn) sendEvent(ev *pubsubConnEvent) {
+	select {
+	case c.events <- ev:
+	case <-c.pool.twitch.ctx.Done():
+	}
+}
+
+// handles an event from a read goroutine
+func (c *PUBSUBConn) handleEvent(ev *pubsubConnEvent) {
+	// ignore anything from a websocket that's already been dropped
+	if ev.ws != c.ws {
+		return
+	}
+
+	// the websocket died on its own
+	if ev.err != nil {
+		if websocket.IsUnexpectedCloseError(ev.err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			log.Printf("[ERROR] pubsub: conn [%d]: unexpected close error: %s", c.id, ev.err)
+		}
+		c.disconnect(fmt.Sprintf("read: %s", ev.err))
+		return
+	}
+
+	c.lastMessage = time.Now()
+
+	switch ev.msg.Type {
+	case PUBSUBTypePong:
+		log.Printf("[INFO] pubsub: conn [%d]: PONG received in time", c.id)
+		c.pongTimer = stopTimer(c.pongTimer)
+		c.pongMissed = false
+	case PUBSUBTypeReconnect:
+		log.Printf("[INFO] pubsub: conn [%d]: reconnect alert received", c.id)
+		c.disconnect("reconnect alert")
+		return
+	case PUBSUBTypeResponse:
+		c.listenFailed = len(ev.msg.Error) > 0
+	}
+
+	c.updateHealth()
+}
+
+// sends a ping to twitch and waits for the PONG
+func (c *PUBSUBConn) ping() {
+	c.pingTimer = stopTimer(c.pingTimer)
+
+	c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+
+	// send ws ping
+	if err := c.ws.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+		c.disconnect(fmt.Sprintf("sending ping: %s", err))
+		return
+	}
+
+	// convert twitch ping to bytes
+	ping := &PUBSUBMessage{
+		Type: PUBSUBTypePing,
+	}
+	pingBytes, err := ping.ToBytes()
+	if err != nil {
+		log.Printf("[ERROR] unable to generate ping: %s", err)
+		return
+	}
+
+	// send twitch ping
+	c.write(pingBytes)
+	if c.ws == nil {
+		return
+	}
+
+	// wait for the next ping and the PONG
+	c.pingTimer = time.NewTimer(pingPeriod)
+	if c.pongTimer == nil {
+		c.pongTimer = time.NewTimer(pongWait)
+	}
+}
+
+// gives a connection one more ping when a PONG is late,
+// reconnecting if that one is late too
+func (c *PUBSUBConn) pongTimeout() {
+	if c.pongMissed {
+		log.Printf("[INFO] pubsub: conn [%d]: PONG timeout: reconnecting", c.id)
+		c.disconnect("PONG timeout")
+		return
+	}
+
+	log.Printf("[INFO] pubsub: conn [%d]: PONG timeout: pinging again", c.id)
+	c.pongMissed = true
+	c.updateHealth()
+
+	c.ping()
+}
+
+// writes a request on the websocket, dropping it while disconnected
+// since the pool listens again once the connection is back
+func (c *PUBSUBConn) write(message []byte) {
+	if c.ws == nil {
+		return
+	}
+
+	c.ws.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := c.ws.WriteMessage(websocket.TextMessage, message); err != nil {
+		c.disconnect(fmt.Sprintf("write: %s", err))
+	}
+}
+
+// moves between listening and degraded while connected
+func (c *PUBSUBConn) updateHealth() {
+	state := c.state
+	if c.ws != nil {
+		state = PUBSUBConnStateListening
+		if c.pongMissed || c.listenFailed {
+			state = PUBSUBConnStateDegraded
+		}
+	}
+
+	c.setState(state)
+}
+
+// closes the websocket and backs off before dialing again, handing
+// the connection topics to the rest of the pool while it's down
+func (c *PUBSUBConn) disconnect(reason string) {
+	log.Printf("[INFO] pubsub: conn [%d]: disconnected: %s", c.id, reason)
+
+	c.ws.Close()
+	c.ws = nil
+	c.pingTimer = stopTimer(c.pingTimer)
+	c.pongTimer = stopTimer(c.pongTimer)
+	c.reconnects++
+	c.downSince = time.Now()
+
+	c.backOff()
+
+	// move topics to the other connections, the pool handles changes
+	// in order without holding up the run goroutine
+	c.pool.connChanged(&pubsubConnChange{conn: c, downSince: c.downSince})
+}
+
+// closes the websocket and stops every timer on shutdown
+func (c *PUBSUBConn) close() {
+	log.Printf("[INFO] pubsub: conn [%d]: closing", c.id)
+
+	if c.ws != nil {
+		c.ws.Close()
+		c.ws = nil
+	}
+	c.pingTimer = stopTimer(c.pingTimer)
+	c.pongTimer = stopTimer(c.pongTimer)
+	c.retryTimer = stopTimer(c.retryTimer)
+
+	c.setState(PUBSUBConnStateClosed)
+}
+
+// waits for the backoff before dialing again
+func (c *PUBSUBConn) backOff() {
+	wait := c.Backoff.Duration()
+
+	c.nextRetry = time.Now().Add(wait)
+	c.retryTimer = time.NewTimer(wait)
+
+	c.setState(PUBSUBConnStateBackingOff)
+}
+
+// dials twitch again once backed off
+func (c *PUBSUBConn) retry() {
+	c.setState(PUBSUBConnStateConnecting)
+
+	ws, err := c.dial()
+	if err != nil {
+		log.Printf("[ERROR] pubsub: conn [%d]: connect: %s", c.id, err)
+		c.backOff()
+		return
+	}
+
+	c.connected(ws)
+
+	// listen for the topics on this connection again
+	c.pool.connChanged(&pubsubConnChange{conn: c, restored: true})
+}
+
+// moves to a state and publishes the status
+func (c *PUBSUBConn) setState(state PUBSUBConnState) {
+	if state != c.state {
+		log.Printf("[INFO] pubsub: conn [%d]: %s -> %s", c.id, c.state, state)
+	}
+	c.state = state
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.status.State = c.state
+	c.status.Reconnects = c.reconnects
+	c.status.LastMessage = timeOrNil(c.lastMessage)
+	c.status.NextRetry = timeOrNil(c.nextRetry)
+}
+
+// returns the channel of a timer, or nil so a select never picks it
+func timerC(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+
+	return t.C
+}
+
+// stops a timer if there is one, returning nil to clear it with
+func stopTimer(t *time.Timer) *time.Timer {
+	if t != nil {
+		t.Stop()
+	}
+
+	return nil
+}
+
+// returns a pointer to a time, or nil for the zero time
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
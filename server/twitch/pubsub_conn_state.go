@@ -0,0 +1,21 @@
+package twitch
+
+// PUBSUBConnState is a state in the life of a pub sub connection.
+type PUBSUBConnState string
+
+const (
+	// dialing twitch
+	PUBSUBConnStateConnecting PUBSUBConnState = "connecting"
+	// connected with healthy heartbeats and listens
+	PUBSUBConnStateListening PUBSUBConnState = "listening"
+	// connected, but a PONG is overdue or a listen failed
+	PUBSUBConnStateDegraded PUBSUBConnState = "degraded"
+	// disconnected, waiting to dial again
+	PUBSUBConnStateBackingOff PUBSUBConnState = "backing_off"
+	// closed on shutdown
+	PUBSUBConnStateClosed PUBSUBConnState = "closed"
+)
+
+func (s PUBSUBConnState) String() string {
+	return string(s)
+}
@@ -0,0 +1,106 @@
+package twitch
+
+import (
+	"log"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+var pubsubJournalBuffer = 1024
+
+// MetricJournalDropped counts frames dropped with the journal queue full.
+const MetricJournalDropped = "pubsub.journal.dropped"
+
+// journalWriter saves journaled frames, the database outside of tests.
+type journalWriter interface {
+	AddJournalEntry(e *database.JournalEntry) error
+}
+
+// queues a raw frame to be journaled before it's parsed. Connections
+// never wait on the database, so frames are dropped while it's too
+// slow to keep up.
+func (p *PUBSUB) journal(connID int, frame []byte) {
+	if p.journalWriter == nil {
+		return
+	}
+
+	entry := &database.JournalEntry{
+		ConnID:     connID,
+		ReceivedAt: time.Now(),
+		Frame:      string(frame),
+	}
+
+	p.journalMu.RLock()
+	defer p.journalMu.RUnlock()
+
+	if p.journalClosed {
+		return
+	}
+
+	select {
+	case p.journalQueue <- entry:
+	default:
+		log.Printf("[ERROR] pubsub: conn [%d]: journal: queue full, dropping frame", connID)
+		p.twitch.metrics.Inc(MetricJournalDropped)
+	}
+}
+
+// saves queued frames to the journal until the queue is closed
+func (p *PUBSUB) runJournal() {
+	for entry := range p.journalQueue {
+		if err := p.journalWriter.AddJournalEntry(entry); err != nil {
+			log.Printf("[ERROR] pubsub: conn [%d]: journal: %s", entry.ConnID, err)
+		}
+	}
+}
+
+// stops journaling, the frames already queued are still saved
+func (p *PUBSUB) closeJournal() {
+	p.journalMu.Lock()
+	defer p.journalMu.Unlock()
+
+	if p.journalClosed || p.journalQueue == nil {
+		return
+	}
+
+	p.journalClosed = true
+	close(p.journalQueue)
+}
+
+// Replay feeds the MESSAGE frames journaled within the window back
+// through the message handler, returning how many were replayed. A dry
+// run decodes them the same way, logging what fails instead of keeping
+// dead letters, and should be given a bus that doesn't save. Responses
+// and heartbeats belonged to connections long gone, so they're skipped.
+func (p *PUBSUB) Replay(from time.Time, to time.Time, dryRun bool) (int, error) {
+	replayed := 0
+
+	err := p.database.GetJournalEntries(from, to, func(entry *database.JournalEntry) error {
+		// convert bytes to message
+		msg, err := NewPUBSUBMessage([]byte(entry.Frame))
+		if err != nil {
+			log.Printf("[ERROR] replay: frame [%s]: %s: %s", entry.ID.Hex(), err, entry.Frame)
+			return nil
+		}
+
+		if msg.Type != PUBSUBTypeMessage || msg.Data == nil {
+			return nil
+		}
+
+		log.Printf("[INFO] replay: frame [%s]: conn [%d]: %s: %s", entry.ID.Hex(), entry.ConnID, entry.ReceivedAt.Format(time.RFC3339), msg.Data.Topic)
+
+		if dryRun {
+			if err := p.handleMessage(msg.Data.Topic, msg.Data.Message); err != nil {
+				log.Printf("[ERROR] replay: frame [%s]: topic [%s]: %s", entry.ID.Hex(), msg.Data.Topic, err)
+			}
+		} else {
+			p.handleWSMessage(nil, msg)
+		}
+		replayed++
+
+		return nil
+	})
+
+	return replayed, err
+}
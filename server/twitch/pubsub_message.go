@@ -7,8 +7,9 @@ import (
 // PUBSUBMessage is a message when a pub sub event is received.
 type PUBSUBMessage struct {
 	Type  PUBSUBType         `json:"type"`
-	Data  *PUBSUBMessageData `json:"data",omitempty`
-	Error string             `json:"error",omitempty`
+	Nonce string             `json:"nonce,omitempty"`
+	Data  *PUBSUBMessageData `json:"data,omitempty"`
+	Error string             `json:"error,omitempty"`
 }
 
 // PUBSUBMessageData is the data on a pub sub message.
@@ -54,11 +55,19 @@ type PUBSUBSubscriptionMessage struct {
 	SubPlan     string `json:"sub_plan"`
 	SubPlanName string `json:"sub_plan_name"`
 	Months      int    `json:"months"`
+	Cumulative  int    `json:"cumulative_months"`
+	Streak      int    `json:"streak_months"`
 	Context     string `json:"context"`
 	SubMessage  struct {
 		Message string         `json:"message"`
 		Emotes  []*PUBSUBEmote `json:"emotes"`
 	} `json:"sub_message"`
+
+	IsGift               bool   `json:"is_gift"`
+	RecipientID          string `json:"recipient_id"`
+	RecipientUserName    string `json:"recipient_user_name"`
+	RecipientDisplayName string `json:"recipient_display_name"`
+	MultiMonthDuration   int    `json:"multi_month_duration"`
 }
 
 // NewPUBSUBSubscriptionMessage returns a new subscription message.
@@ -142,6 +151,58 @@ type PUBSUBCommerceMessage struct {
 	} `json:"purchase_message"`
 }
 
-// PUBSUBWhisperMessage is a message for a whisper
+// NewPUBSUBCommerceMessage returns a new commerce message.
+func NewPUBSUBCommerceMessage(message string) (*PUBSUBCommerceMessage, error) {
+	var commerce PUBSUBCommerceMessage
+
+	// marshal message
+	if err := json.Unmarshal([]byte(message), &commerce); err != nil {
+		return nil, err
+	}
+
+	// return commerce
+	return &commerce, nil
+}
+
+// PUBSUBRedemptionMessage is a message for a channel points
 // event on a pub sub message.
-type PUBSUBWhisperMessage struct{}
+type PUBSUBRedemptionMessage struct {
+	Type string                       `json:"type"`
+	Data *PUBSUBRedemptionMessageData `json:"data"`
+}
+
+// PUBSUBRedemptionMessageData is the data for a PUBSUBRedemptionMessage.
+type PUBSUBRedemptionMessageData struct {
+	Timestamp  string `json:"timestamp"`
+	Redemption struct {
+		ID   string `json:"id"`
+		User struct {
+			ID          string `json:"id"`
+			Login       string `json:"login"`
+			DisplayName string `json:"display_name"`
+		} `json:"user"`
+		ChannelID  string `json:"channel_id"`
+		RedeemedAt string `json:"redeemed_at"`
+		Reward     struct {
+			ID     string `json:"id"`
+			Title  string `json:"title"`
+			Prompt string `json:"prompt"`
+			Cost   int    `json:"cost"`
+		} `json:"reward"`
+		UserInput string `json:"user_input"`
+		Status    string `json:"status"`
+	} `json:"redemption"`
+}
+
+// NewPUBSUBRedemptionMessage returns a new redemption message.
+func NewPUBSUBRedemptionMessage(message string) (*PUBSUBRedemptionMessage, error) {
+	var redemption PUBSUBRedemptionMessage
+
+	// marshal message
+	if err := json.Unmarshal([]byte(message), &redemption); err != nil {
+		return nil, err
+	}
+
+	// return redemption
+	return &redemption, nil
+}
@@ -0,0 +1,300 @@
+package twitch
+
+import (
+	"log"
+	"time"
+)
+
+var (
+	// how long twitch has to answer a request
+	pubsubResponseTimeout = 10 * time.Second
+	// wait before retrying a failed topic, multiplied by its failures
+	pubsubTopicRetryDelay = 5 * time.Second
+)
+
+// pubsubPending is a request waiting on its twitch response.
+type pubsubPending struct {
+	channelID string
+	reqType   PUBSUBType
+	topics    []string
+	conn      *PUBSUBConn
+	timer     *time.Timer
+}
+
+// PUBSUBBrokenTopic is a topic given up on after failing repeatedly.
+type PUBSUBBrokenTopic struct {
+	ChannelID string `json:"channel_id"`
+	Topic     string `json:"topic"`
+	Error     string `json:"error"`
+}
+
+// sends a request for some of a channel's topics, one for each
+// connection they're on, must be called with p.mu held
+func (p *PUBSUB) sendRequest(channel *PUBSUBChannel, reqType PUBSUBType, topics []string) error {
+	// group the topics by connection
+	connTopics := make(map[*PUBSUBConn][]string)
+	for _, topic := range topics {
+		if conn := channel.conns[topic]; conn != nil {
+			connTopics[conn] = append(connTopics[conn], topic)
+		}
+	}
+
+	for _, conn := range p.conns {
+		if err := p.sendConnRequest(channel, conn, reqType, connTopics[conn]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sends a request for some of a channel's topics on a connection,
+// tracking it by nonce until twitch responds, must be called with p.mu held
+func (p *PUBSUB) sendConnRequest(channel *PUBSUBChannel, conn *PUBSUBConn, reqType PUBSUBType, topics []string) error {
+	// the topics will be listened for once the connection is back
+	if !conn.isConnected() || len(topics) == 0 {
+		return nil
+	}
+
+	// create request
+	nonce := newNonce()
+	oauthToken, _ := p.config.ChannelTokens(channel.channel)
+	req := NewPUBSUBRequest(reqType.String(), nonce, topics, oauthToken)
+
+	// convert request to bytes
+	reqBytes, err := req.ToBytes()
+	if err != nil {
+		return err
+	}
+
+	// track the request until it's answered or times out
+	p.pending[nonce] = &pubsubPending{
+		channelID: channel.channel.ID,
+		reqType:   reqType,
+		topics:    topics,
+		conn:      conn,
+		timer: time.AfterFunc(pubsubResponseTimeout, func() {
+			p.handleResponseTimeout(nonce)
+		}),
+	}
+
+	// send request
+	conn.Send <- reqBytes
+
+	return nil
+}
+
+// removes a pending request, must be called with p.mu held
+func (p *PUBSUB) takePending(nonce string) (*pubsubPending, bool) {
+	pending, ok := p.pending[nonce]
+	if !ok {
+		return nil, false
+	}
+
+	pending.timer.Stop()
+	delete(p.pending, nonce)
+
+	return pending, true
+}
+
+// forgets the requests sent on a lost connection, since they'll never
+// be answered, must be called with p.mu held
+func (p *PUBSUB) dropPending(conn *PUBSUBConn) {
+	for nonce, pending := range p.pending {
+		if pending.conn == conn {
+			p.takePending(nonce)
+		}
+	}
+}
+
+// handles the response to a pending request
+func (p *PUBSUB) handleResponse(nonce string, respErr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// find the request the response is for
+	pending, ok := p.takePending(nonce)
+	if !ok {
+		if len(respErr) > 0 {
+			log.Printf("[ERROR] pubsub: response for unknown nonce [%s]: %s", nonce, respErr)
+		}
+		return
+	}
+
+	channel, ok := p.channels[pending.channelID]
+	if !ok {
+		return
+	}
+
+	// nothing to retry for a failed unlisten
+	if pending.reqType == PUBSUBTypeUnListen {
+		if len(respErr) > 0 {
+			log.Printf("[ERROR] pubsub: channel [%s]: unlisten %v: %s", pending.channelID, pending.topics, respErr)
+		}
+		return
+	}
+
+	// successful listen for the topics
+	if len(respErr) == 0 {
+		channel.badAuth = 0
+		for _, topic := range pending.topics {
+			channel.topicListened(topic)
+		}
+		p.channelListened(channel)
+		return
+	}
+
+	p.handleResponseError(channel, pending.topics, respErr)
+}
+
+// backfills a channel listened to again after a gap, or takes the
+// baseline to backfill with for one listened to the first time, must
+// be called with p.mu held
+func (p *PUBSUB) channelListened(channel *PUBSUBChannel) {
+	if p.twitch.backfill == nil {
+		return
+	}
+
+	channelID := channel.channel.ID
+
+	switch {
+	case !channel.downSince.IsZero():
+		from := channel.downSince
+		channel.downSince = time.Time{}
+		p.backfill(channel, false, func() bool {
+			return p.twitch.backfill.Gap(channelID, from, time.Now())
+		})
+	case !channel.baselined && !channel.baselining:
+		p.backfill(channel, true, func() bool {
+			return p.twitch.backfill.Baseline(channelID)
+		})
+	}
+}
+
+// runs a backfill of a channel, which is baselined once one succeeds,
+// must be called with p.mu held
+func (p *PUBSUB) backfill(channel *PUBSUBChannel, baselining bool, fn func() bool) {
+	if baselining {
+		channel.baselining = true
+	}
+
+	go func() {
+		baselined := fn()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if baselining {
+			channel.baselining = false
+		}
+		if baselined {
+			channel.baselined = true
+		}
+	}()
+}
+
+// handles a request twitch never answered
+func (p *PUBSUB) handleResponseTimeout(nonce string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pending, ok := p.takePending(nonce)
+	if !ok {
+		return
+	}
+
+	log.Printf("[ERROR] pubsub: channel [%s]: %s %v: response timeout", pending.channelID, pending.reqType, pending.topics)
+
+	// only listens are retried
+	channel, ok := p.channels[pending.channelID]
+	if !ok || pending.reqType != PUBSUBTypeListen {
+		return
+	}
+
+	p.retryTopics(channel, pending.topics, "response timeout")
+}
+
+// handles a failed listen, must be called with p.mu held
+func (p *PUBSUB) handleResponseError(channel *PUBSUBChannel, topics []string, err string) {
+	channelID := channel.channel.ID
+
+	switch PUBSUBMessageError(err) {
+	// bad auth token
+	case errBadAuth:
+		log.Printf("[ERROR] pubsub: response: channel [%s]: bad auth: %s", channelID, err)
+
+		// refresh the token and listen again for only this channel
+		go p.relistenChannel(channel)
+		return
+	case errBadMessage:
+		log.Printf("[ERROR] pubsub: response: channel [%s]: bad message %v: %s", channelID, topics, err)
+	case errBadTopic:
+		log.Printf("[ERROR] pubsub: response: channel [%s]: bad topic %v: %s", channelID, topics, err)
+	case errServer2:
+		fallthrough
+	case errServer:
+		log.Printf("[ERROR] pubsub: response: channel [%s]: server %v: %s", channelID, topics, err)
+	}
+
+	p.retryTopics(channel, topics, err)
+}
+
+// retries each failed topic on its own, since a failed request doesn't
+// say which of its topics was bad, giving up on topics that keep
+// failing, must be called with p.mu held
+func (p *PUBSUB) retryTopics(channel *PUBSUBChannel, topics []string, err string) {
+	channelID := channel.channel.ID
+
+	for _, topic := range topics {
+		failures := channel.topicFailed(topic, err)
+		if channel.topicBroken(topic) {
+			log.Printf("[ERROR] pubsub: channel [%s]: giving up on topic [%s] after %d failures: %s", channelID, topic, failures, err)
+			continue
+		}
+
+		// wait longer after each failure
+		topic := topic
+		time.AfterFunc(pubsubTopicRetryDelay*time.Duration(failures), func() {
+			p.retryTopic(channelID, topic)
+		})
+	}
+}
+
+// sends the listen request again for a single failed topic
+func (p *PUBSUB) retryTopic(channelID string, topic string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// skip channels and topics that have gone away
+	channel, ok := p.channels[channelID]
+	if !ok || channel.topicBroken(topic) || !channel.hasTopic(topic) {
+		return
+	}
+
+	log.Printf("[INFO] pubsub: channel [%s]: retrying topic [%s]", channelID, topic)
+
+	if err := p.sendRequest(channel, PUBSUBTypeListen, []string{topic}); err != nil {
+		log.Printf("[ERROR] pubsub: channel [%s]: listen request: %s", channelID, err)
+	}
+}
+
+// BrokenTopics returns the topics given up on after failing repeatedly.
+func (p *PUBSUB) BrokenTopics() []*PUBSUBBrokenTopic {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	broken := make([]*PUBSUBBrokenTopic, 0)
+	for _, channel := range p.sortedChannels() {
+		for _, topic := range channel.topics() {
+			if err, ok := channel.broken[topic]; ok {
+				broken = append(broken, &PUBSUBBrokenTopic{
+					ChannelID: channel.channel.ID,
+					Topic:     topic,
+					Error:     err,
+				})
+			}
+		}
+	}
+
+	return broken
+}
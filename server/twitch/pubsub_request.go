@@ -1,11 +1,16 @@
 package twitch
 
-import "encoding/json"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+)
 
 // PUBSUBRequest is a twitch pub sub request.
 type PUBSUBRequest struct {
-	Type string             `json:"type"`
-	Data *PUBSUBRequestData `json:"data"`
+	Type  string             `json:"type"`
+	Nonce string             `json:"nonce,omitempty"`
+	Data  *PUBSUBRequestData `json:"data"`
 }
 
 // PUBSUBRequestData is the payload on a twitch pub sub request.
@@ -15,9 +20,10 @@ type PUBSUBRequestData struct {
 }
 
 // NewPUBSUBRequest returns a new pub sub request.
-func NewPUBSUBRequest(requestType string, topics []string, authToken string) *PUBSUBRequest {
+func NewPUBSUBRequest(requestType string, nonce string, topics []string, authToken string) *PUBSUBRequest {
 	return &PUBSUBRequest{
-		Type: requestType,
+		Type:  requestType,
+		Nonce: nonce,
 		Data: &PUBSUBRequestData{
 			Topics:    topics,
 			AuthToken: authToken,
@@ -36,3 +42,11 @@ func (r *PUBSUBRequest) ToBytes() ([]byte, error) {
 	// return request bytes
 	return data, nil
 }
+
+// newNonce returns a random nonce for a pub sub request.
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,186 @@
+package twitch
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/faketwitch"
+	"github.com/codephobia/twitch-eos-thanks/server/config"
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+	"github.com/codephobia/twitch-eos-thanks/server/metrics"
+)
+
+// captureSink keeps the events published to the bus in place of saving them.
+type captureSink struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (s *captureSink) HandleEvent(e events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, e)
+	return nil
+}
+
+// waits for an event matching fn to be published
+func (s *captureSink) wait(t *testing.T, fn func(events.Event) bool) events.Event {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		for _, e := range s.events {
+			if fn(e) {
+				s.mu.Unlock()
+				return e
+			}
+		}
+		s.mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("timed out waiting for event")
+	return nil
+}
+
+// captureJournal keeps journaled frames in place of saving them.
+type captureJournal struct {
+	mu     sync.Mutex
+	frames []string
+}
+
+func (j *captureJournal) AddJournalEntry(e *database.JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.frames = append(j.frames, e.Frame)
+	return nil
+}
+
+// waits for a frame containing s to be journaled
+func (j *captureJournal) wait(t *testing.T, s string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		j.mu.Lock()
+		for _, frame := range j.frames {
+			if strings.Contains(frame, s) {
+				j.mu.Unlock()
+				return
+			}
+		}
+		j.mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for journaled frame with %s", s)
+}
+
+// waits for the fake to be listening to a topic
+func waitListening(t *testing.T, fake *faketwitch.Server, topic string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, listening := range fake.Listening() {
+			if listening == topic {
+				return
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for listen on %s", topic)
+}
+
+func TestPubSubEndToEnd(t *testing.T) {
+	// fake twitch with a token for the channel
+	fake := faketwitch.NewServer()
+	fake.AddToken(&faketwitch.Token{AccessToken: "channel-token", UserID: "1001"})
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	c := &config.Config{
+		TwitchAPIURL:    srv.URL,
+		TwitchOAuthURL:  srv.URL + "/oauth2",
+		TwitchPubSubURL: "ws" + strings.TrimPrefix(srv.URL, "http") + "/pubsub",
+		Channels: []*config.Channel{
+			{
+				ID:         "1001",
+				Transport:  config.TransportPubSub,
+				OAuthToken: "channel-token",
+			},
+		},
+	}
+
+	// events are captured in place of the database
+	m := metrics.NewMetrics()
+	bus := events.NewBus(m)
+	sink := &captureSink{}
+	bus.Subscribe("storage", sink, 0)
+	defer bus.Close()
+
+	tw := NewTwitch(c, nil, m, bus)
+	defer tw.Close()
+
+	// frames are journaled in place of the database
+	journal := &captureJournal{}
+	tw.pubsub.journalWriter = journal
+
+	if err := tw.pubsub.Init(); err != nil {
+		t.Fatalf("init: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		topic   string
+		message string
+		match   func(events.Event) bool
+	}{
+		{
+			name:    "sub",
+			topic:   "channel-subscribe-events-v1.1001",
+			message: `{"user_name":"viewer","display_name":"Viewer","channel_name":"streamer","user_id":"2002","channel_id":"1001","time":"2020-01-01T00:00:00Z","sub_plan":"1000","sub_plan_name":"Tier 1","cumulative_months":3,"streak_months":2,"context":"resub","sub_message":{"message":"hi","emotes":[]}}`,
+			match: func(e events.Event) bool {
+				sub, ok := e.(*events.Subscription)
+				return ok && sub.UserID == "2002" && sub.Months == 3 && sub.ChannelID == "1001"
+			},
+		},
+		{
+			name:    "bits",
+			topic:   "channel-bits-events-v1.1001",
+			message: `{"data":{"user_name":"cheerer","channel_name":"streamer","user_id":"3003","channel_id":"1001","time":"2020-01-01T00:00:00Z","chat_message":"cheer100","bits_used":100,"total_bits_used":500,"context":"cheer"},"version":"1.0","message_type":"bits_event","message_id":"bits-message-1"}`,
+			match: func(e events.Event) bool {
+				cheer, ok := e.(*events.Cheer)
+				return ok && cheer.UserID == "3003" && cheer.Bits == 100 && cheer.MessageID == "bits-message-1"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waitListening(t, fake, tt.topic)
+
+			if sent := fake.Publish(tt.topic, tt.message); sent != 1 {
+				t.Fatalf("published to %d connections, want 1", sent)
+			}
+
+			journal.wait(t, tt.topic)
+
+			e := sink.wait(t, tt.match)
+			if e.Metadata().Source != events.SourcePubSub {
+				t.Errorf("source = %s, want %s", e.Metadata().Source, events.SourcePubSub)
+			}
+		})
+	}
+}
@@ -7,7 +7,7 @@ const (
 	PUBSUBTopicSubscription PUBSUBTopic = "channel-subscribe-events-v1"
 	PUBSUBTopicBits         PUBSUBTopic = "channel-bits-events-v1"
 	PUBSUBTopicCommerce     PUBSUBTopic = "channel-commerce-events-v1"
-	PUBSUBTopicWhispers     PUBSUBTopic = "whispers"
+	PUBSUBTopicRedemption   PUBSUBTopic = "channel-points-channel-v1"
 )
 
 func (t PUBSUBTopic) String() string {
@@ -0,0 +1,188 @@
+package twitch
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+var (
+	// ErrUnknownChannel is returned for channels that aren't on pub sub.
+	ErrUnknownChannel = errors.New("unknown pub sub channel")
+	// ErrUnknownTopic is returned for topics pub sub can't listen for.
+	ErrUnknownTopic = errors.New("unknown pub sub topic")
+	// ErrConnFull is returned when a topic doesn't fit on the pool.
+	ErrConnFull = errors.New("pub sub connection is full")
+
+	// topics that can be listened for. Whispers aren't among them,
+	// they're private messages to the broadcaster rather than support
+	// for the channel, so there's nothing to thank anyone for and
+	// they're better not kept.
+	pubsubTopics = []PUBSUBTopic{
+		PUBSUBTopicSubscription,
+		PUBSUBTopicBits,
+		PUBSUBTopicCommerce,
+		PUBSUBTopicRedemption,
+	}
+
+	// topics listened for on channels that haven't chosen their own
+	pubsubDefaultTopics = []PUBSUBTopic{
+		PUBSUBTopicSubscription,
+		PUBSUBTopicBits,
+		PUBSUBTopicCommerce,
+		PUBSUBTopicRedemption,
+	}
+)
+
+// PUBSUBChannelTopics reports the topics of a channel.
+type PUBSUBChannelTopics struct {
+	ChannelID string               `json:"channel_id"`
+	Topics    []*PUBSUBTopicStatus `json:"topics"`
+}
+
+// PUBSUBTopicStatus reports the listen state of a topic.
+type PUBSUBTopicStatus struct {
+	Topic    string `json:"topic"`
+	Failures int    `json:"failures"`
+	Broken   bool   `json:"broken"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Topics returns the topics of every channel, or of a single channel
+// when a channel id is given.
+func (p *PUBSUB) Topics(channelID string) ([]*PUBSUBChannelTopics, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channels := p.sortedChannels()
+	if len(channelID) > 0 {
+		channel, ok := p.channels[channelID]
+		if !ok {
+			return nil, ErrUnknownChannel
+		}
+		channels = []*PUBSUBChannel{channel}
+	}
+
+	resp := make([]*PUBSUBChannelTopics, 0, len(channels))
+	for _, channel := range channels {
+		channelTopics := &PUBSUBChannelTopics{
+			ChannelID: channel.channel.ID,
+			Topics:    make([]*PUBSUBTopicStatus, 0),
+		}
+
+		for _, topic := range channel.topics() {
+			err, broken := channel.broken[topic]
+			channelTopics.Topics = append(channelTopics.Topics, &PUBSUBTopicStatus{
+				Topic:    topic,
+				Failures: channel.failures[topic],
+				Broken:   broken,
+				Error:    err,
+			})
+		}
+
+		resp = append(resp, channelTopics)
+	}
+
+	return resp, nil
+}
+
+// AddTopic listens for a topic on a channel over its live connection,
+// saving it to the channel topics.
+func (p *PUBSUB) AddTopic(channelID string, topic PUBSUBTopic) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channel, ok := p.channels[channelID]
+	if !ok {
+		return ErrUnknownChannel
+	}
+	if !validTopic(topic) {
+		return ErrUnknownTopic
+	}
+
+	// nothing to do if the channel already has the topic
+	names := channel.topicNames()
+	for _, name := range names {
+		if name == topic.String() {
+			return nil
+		}
+	}
+
+	// make sure the topic fits on the pool
+	if !p.hasRoom(1) {
+		return ErrConnFull
+	}
+
+	log.Printf("[INFO] pubsub: channel [%s]: adding topic [%s]", channelID, topic)
+
+	// save the new topic set
+	if err := p.config.SetChannelTopics(channel.channel, append(names, topic.String())); err != nil {
+		return fmt.Errorf("save config: %s", err)
+	}
+
+	// listen on the least loaded connection
+	p.placeTopics(channel)
+	return p.sendRequest(channel, PUBSUBTypeListen, []string{channel.topicName(topic)})
+}
+
+// RemoveTopic stops listening for a topic on a channel over its live
+// connection, removing it from the channel topics.
+func (p *PUBSUB) RemoveTopic(channelID string, topic PUBSUBTopic) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	channel, ok := p.channels[channelID]
+	if !ok {
+		return ErrUnknownChannel
+	}
+	if !validTopic(topic) {
+		return ErrUnknownTopic
+	}
+
+	// keep every other topic
+	names := make([]string, 0)
+	found := false
+	for _, name := range channel.topicNames() {
+		if name == topic.String() {
+			found = true
+			continue
+		}
+		names = append(names, name)
+	}
+
+	// nothing to do if the channel doesn't have the topic
+	if !found {
+		return nil
+	}
+
+	log.Printf("[INFO] pubsub: channel [%s]: removing topic [%s]", channelID, topic)
+
+	// broken topics were never listened for
+	fullTopic := channel.topicName(topic)
+	unlisten := !channel.topicBroken(fullTopic)
+
+	// save the new topic set
+	if err := p.config.SetChannelTopics(channel.channel, names); err != nil {
+		return fmt.Errorf("save config: %s", err)
+	}
+
+	// unlisten on the connection the topic was on
+	var err error
+	if unlisten {
+		err = p.sendRequest(channel, PUBSUBTypeUnListen, []string{fullTopic})
+	}
+	channel.forgetTopic(fullTopic)
+
+	return err
+}
+
+// checks if pub sub can listen for a topic
+func validTopic(topic PUBSUBTopic) bool {
+	for _, t := range pubsubTopics {
+		if t == topic {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,47 +1,22 @@
 package twitch
 
 import (
-	"fmt"
-	"io/ioutil"
+	"context"
 	"net/http"
 	"strings"
 )
 
 // get a twitch response
-func (t *Twitch) getTwitchResponse(version TwitchVersion, urlSuffix string) ([]byte, error) {
-	// create http client
-	client := &http.Client{}
-
+func (t *Twitch) getTwitchResponse(ctx context.Context, version TwitchVersion, urlSuffix string) ([]byte, error) {
 	// build url with version prefix / suffix
-	url := strings.Join([]string{version.Url(), urlSuffix}, "")
-
-	// create new request
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("error generating request: %v", err)
-	}
-
-	// add oauth token and client id to headers
-	req.Header.Add("Authorization", t.config.TwitchOAuthToken)
-	req.Header.Add("Client-ID", t.config.TwitchClientID)
+	url := strings.Join([]string{version.Url(t.config.APIURL()), urlSuffix}, "")
 
 	// add accept header for V5 api calls
+	header := http.Header{}
 	if version == TwitchV5 {
-		req.Header.Add("Accept", "application/vnd.twitchtv.v5+json")
+		header.Set("Accept", "application/vnd.twitchtv.v5+json")
 	}
 
 	// do get request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("error doing request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// read body
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("error reading body: %v", err)
-	}
-
-	return data, nil
+	return t.helix.Get(ctx, url, header)
 }
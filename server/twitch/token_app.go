@@ -0,0 +1,113 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// key app token refreshes are single flighted under, channel ids
+	// are numeric so they never collide with it
+	appTokenKey = "app"
+	// renew the app token this long before it expires, or halfway
+	// through its life for tokens that don't last twice as long
+	appTokenRenewBefore = 1 * time.Hour
+)
+
+// AppTokenSource is a helix token source for the app access token.
+type AppTokenSource struct {
+	tokens *TokenManager
+}
+
+// Token returns the app access token authorization header.
+func (s *AppTokenSource) Token(ctx context.Context) (string, error) {
+	token, err := s.tokens.AppToken()
+	if err != nil {
+		return "", err
+	}
+
+	return bearerPrefix + token, nil
+}
+
+// Invalidate drops the app access token after twitch rejected it.
+func (s *AppTokenSource) Invalidate(token string) {
+	s.tokens.InvalidateAppToken(strings.TrimPrefix(token, bearerPrefix))
+}
+
+// AppToken returns the app access token, getting a new one through
+// the client credentials grant when it's missing or about to expire.
+func (m *TokenManager) AppToken() (string, error) {
+	if token, ok := m.currentAppToken(); ok {
+		return token, nil
+	}
+
+	// get a new token
+	if err := m.singleFlight(appTokenKey, m.refreshAppToken); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.appToken, nil
+}
+
+// InvalidateAppToken drops the app access token if it's still the
+// current one, so the next call to AppToken gets a new one.
+func (m *TokenManager) InvalidateAppToken(token string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.appToken == token {
+		log.Printf("[INFO] tokens: app token rejected: renewing")
+		m.appToken = ""
+	}
+}
+
+// returns the app access token if it isn't about to expire
+func (m *TokenManager) currentAppToken() (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.appToken) == 0 || time.Now().After(m.appTokenRenewAt) {
+		return "", false
+	}
+
+	return m.appToken, true
+}
+
+// gets a new app access token from twitch
+func (m *TokenManager) refreshAppToken() error {
+	// another caller may have renewed it while we waited
+	if _, ok := m.currentAppToken(); ok {
+		return nil
+	}
+
+	tokenResp, err := m.requestToken(url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {m.config.TwitchClientID},
+		"client_secret": {m.config.TwitchClientSecret},
+	})
+	if err != nil {
+		return fmt.Errorf("app token: %s", err)
+	}
+
+	lifetime := time.Duration(tokenResp.ExpiresIn) * time.Second
+	renewBefore := appTokenRenewBefore
+	if lifetime/2 < renewBefore {
+		renewBefore = lifetime / 2
+	}
+
+	m.mu.Lock()
+	m.appToken = tokenResp.AccessToken
+	m.appTokenRenewAt = time.Now().Add(lifetime - renewBefore)
+	m.mu.Unlock()
+
+	log.Printf("[INFO] tokens: app token renewed")
+
+	return nil
+}
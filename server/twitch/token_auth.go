@@ -0,0 +1,166 @@
+package twitch
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/codephobia/twitch-eos-thanks/server/config"
+)
+
+var (
+	tokenAuthorizePath = "/authorize"
+
+	// scopes needed by each pub sub topic
+	pubsubTopicScopes = map[PUBSUBTopic]string{
+		PUBSUBTopicSubscription: "channel:read:subscriptions",
+		PUBSUBTopicBits:         "bits:read",
+		PUBSUBTopicCommerce:     "channel_commerce_read",
+		PUBSUBTopicRedemption:   "channel:read:redemptions",
+	}
+
+	// scopes needed by each eventsub subscription type
+	eventsubTypeScopes = map[EventSubSubscriptionType]string{
+		EventSubSubscriptionTypeSubscribe:           "channel:read:subscriptions",
+		EventSubSubscriptionTypeSubscriptionMessage: "channel:read:subscriptions",
+		EventSubSubscriptionTypeSubscriptionGift:    "channel:read:subscriptions",
+		EventSubSubscriptionTypeCheer:               "bits:read",
+		EventSubSubscriptionTypeFollow:              "moderator:read:followers",
+	}
+)
+
+// Scopes returns the oauth scopes a channel needs for the topics of its
+// transport. Pub sub channels need the scopes of the topics they chose,
+// or of the default topics when topics is nil.
+func Scopes(transport string, topics []string) []string {
+	scopes := make([]string, 0)
+	seen := make(map[string]bool)
+
+	add := func(scope string) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	switch transport {
+	case config.TransportEventSub:
+		for _, t := range []EventSubSubscriptionType{
+			EventSubSubscriptionTypeSubscribe,
+			EventSubSubscriptionTypeSubscriptionMessage,
+			EventSubSubscriptionTypeSubscriptionGift,
+			EventSubSubscriptionTypeCheer,
+			EventSubSubscriptionTypeFollow,
+		} {
+			add(eventsubTypeScopes[t])
+		}
+	default:
+		if topics == nil {
+			for _, t := range pubsubDefaultTopics {
+				topics = append(topics, t.String())
+			}
+		}
+
+		for _, t := range topics {
+			if scope, ok := pubsubTopicScopes[PUBSUBTopic(t)]; ok {
+				add(scope)
+			}
+		}
+	}
+
+	return scopes
+}
+
+// MissingScopes returns the required scopes that weren't granted.
+func MissingScopes(granted []string, required []string) []string {
+	has := make(map[string]bool)
+	for _, scope := range granted {
+		has[scope] = true
+	}
+
+	missing := make([]string, 0)
+	for _, scope := range required {
+		if !has[scope] {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
+
+// AuthorizeURL returns the twitch url to send a user to for
+// authorizing the app with the given scopes.
+func (m *TokenManager) AuthorizeURL(state string, scopes []string) string {
+	params := url.Values{
+		"response_type": {"code"},
+		"client_id":     {m.config.TwitchClientID},
+		"redirect_uri":  {m.config.TwitchRedirectURI},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+	}
+
+	return strings.Join([]string{m.config.OAuthURL(), tokenAuthorizePath, "?", params.Encode()}, "")
+}
+
+// ExchangeCode exchanges an authorization code for channel tokens.
+func (m *TokenManager) ExchangeCode(code string) (*RefreshTokenResp, error) {
+	tokenResp, err := m.requestAuthorizationCode(code)
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %s", err)
+	}
+
+	return tokenResp, nil
+}
+
+// ValidateToken returns the details of an access token, or
+// an error if twitch doesn't accept it.
+func (m *TokenManager) ValidateToken(accessToken string) (*ValidateTokenResp, error) {
+	validateResp, err := m.requestValidateToken(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("validate token: %s", err)
+	}
+
+	// token is invalid
+	if validateResp == nil {
+		return nil, fmt.Errorf("validate token: token invalid")
+	}
+
+	return validateResp, nil
+}
+
+// SetToken stores new tokens for a channel, adding the channel if
+// it isn't configured yet.
+func (m *TokenManager) SetToken(channelID string, accessToken string, refreshToken string) error {
+	channel, ok := m.config.Channel(channelID)
+
+	// new channels are picked up by the listeners on restart
+	if !ok {
+		log.Printf("[INFO] tokens: channel [%s]: adding channel", channelID)
+
+		transport := m.config.TwitchTransport
+		if len(transport) == 0 {
+			transport = config.TransportPubSub
+		}
+
+		channel = m.config.AddChannel(&config.Channel{
+			ID:        channelID,
+			Transport: transport,
+		})
+	}
+
+	// update and save channel tokens
+	if err := m.config.SetChannelTokens(channel, accessToken, refreshToken); err != nil {
+		return fmt.Errorf("save config: %s", err)
+	}
+
+	log.Printf("[INFO] tokens: channel [%s]: token stored", channelID)
+
+	// notify subscribers
+	m.notify(&TokenChange{
+		ChannelID:  channelID,
+		OAuthToken: accessToken,
+	})
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ChannelTokenSource is a helix token source for a channel's user
+// access token, for endpoints only the broadcaster can read.
+type ChannelTokenSource struct {
+	tokens    *TokenManager
+	channelID string
+}
+
+// Token returns the channel access token authorization header.
+func (s *ChannelTokenSource) Token(ctx context.Context) (string, error) {
+	channel, ok := s.tokens.config.Channel(s.channelID)
+	if !ok {
+		return "", fmt.Errorf("unknown channel: %s", s.channelID)
+	}
+
+	oauthToken, _ := s.tokens.config.ChannelTokens(channel)
+
+	return bearerPrefix + oauthToken, nil
+}
+
+// Invalidate refreshes the channel token after twitch rejected it.
+func (s *ChannelTokenSource) Invalidate(token string) {
+	channel, ok := s.tokens.config.Channel(s.channelID)
+	if !ok {
+		return
+	}
+
+	// skip if the token was already refreshed
+	if oauthToken, _ := s.tokens.config.ChannelTokens(channel); oauthToken != strings.TrimPrefix(token, bearerPrefix) {
+		return
+	}
+
+	if err := s.tokens.Refresh(s.channelID); err != nil {
+		log.Printf("[ERROR] tokens: channel [%s]: %s", s.channelID, err)
+	}
+}
@@ -0,0 +1,230 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/config"
+)
+
+var (
+	// twitch asks for tokens to be validated at least once an hour
+	tokenValidatePeriod = 1 * time.Hour
+	// refresh tokens that expire before the next validation
+	tokenRefreshBefore = tokenValidatePeriod + 5*time.Minute
+)
+
+// TokenChange is sent to token subscribers when a channel token changes.
+type TokenChange struct {
+	ChannelID  string
+	OAuthToken string
+}
+
+// TokenManager validates channel tokens on a schedule and refreshes
+// them before they expire.
+type TokenManager struct {
+	config *config.Config
+
+	client *http.Client
+
+	mu          sync.Mutex
+	refreshes   map[string]*tokenRefresh
+	subscribers []func(*TokenChange)
+
+	// app access token from the client credentials grant
+	appToken        string
+	appTokenRenewAt time.Time
+
+	// cancelled on stop, stopping the schedule and requests
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// a refresh in flight for a channel, shared by every caller
+type tokenRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewTokenManager returns a new token manager, stopped when ctx is.
+func NewTokenManager(ctx context.Context, c *config.Config) *TokenManager {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &TokenManager{
+		config: c,
+
+		client: &http.Client{},
+
+		refreshes: make(map[string]*tokenRefresh),
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Init validates the channel tokens and starts the validation schedule.
+func (m *TokenManager) Init() {
+	log.Printf("[INFO] tokens: initializing")
+
+	// validate now so expired tokens are refreshed before listening
+	m.validateAll()
+
+	go m.run()
+}
+
+// Stop stops the validation schedule and cancels the requests in flight.
+func (m *TokenManager) Stop() {
+	m.cancel()
+}
+
+// Subscribe registers a function to be called when a channel token changes.
+func (m *TokenManager) Subscribe(fn func(*TokenChange)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// validates tokens on the schedule until stopped
+func (m *TokenManager) run() {
+	ticker := time.NewTicker(tokenValidatePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.validateAll()
+		}
+	}
+}
+
+// validates every channel token
+func (m *TokenManager) validateAll() {
+	for _, channel := range m.config.ChannelList() {
+		if err := m.Validate(channel.ID); err != nil {
+			log.Printf("[ERROR] tokens: channel [%s]: %s", channel.ID, err)
+		}
+	}
+}
+
+// Validate checks a channel token with twitch and refreshes
+// it if it's invalid or about to expire.
+func (m *TokenManager) Validate(channelID string) error {
+	channel, ok := m.config.Channel(channelID)
+	if !ok {
+		return fmt.Errorf("unknown channel: %s", channelID)
+	}
+
+	// validate token
+	oauthToken, _ := m.config.ChannelTokens(channel)
+	validateResp, err := m.requestValidateToken(oauthToken)
+	if err != nil {
+		return fmt.Errorf("validate token: %s", err)
+	}
+
+	// token is invalid
+	if validateResp == nil {
+		log.Printf("[INFO] tokens: channel [%s]: token invalid: refreshing", channelID)
+		return m.Refresh(channelID)
+	}
+
+	// token expires before the next validation
+	expiresIn := time.Duration(validateResp.ExpiresIn) * time.Second
+	if expiresIn < tokenRefreshBefore {
+		log.Printf("[INFO] tokens: channel [%s]: token expires in %s: refreshing", channelID, expiresIn)
+		return m.Refresh(channelID)
+	}
+
+	return nil
+}
+
+// Refresh refreshes a channel token. Concurrent refreshes for the
+// same channel share a single request to twitch.
+func (m *TokenManager) Refresh(channelID string) error {
+	return m.singleFlight(channelID, func() error {
+		return m.refresh(channelID)
+	})
+}
+
+// runs fn for the key, callers arriving while it runs wait on
+// it and share its result
+func (m *TokenManager) singleFlight(key string, fn func() error) error {
+	m.mu.Lock()
+
+	// wait on the refresh already in flight
+	if refresh, ok := m.refreshes[key]; ok {
+		m.mu.Unlock()
+		<-refresh.done
+		return refresh.err
+	}
+
+	refresh := &tokenRefresh{
+		done: make(chan struct{}),
+	}
+	m.refreshes[key] = refresh
+	m.mu.Unlock()
+
+	// refresh the token
+	refresh.err = fn()
+
+	m.mu.Lock()
+	delete(m.refreshes, key)
+	m.mu.Unlock()
+
+	close(refresh.done)
+
+	return refresh.err
+}
+
+// refresh the access token for a channel from twitch
+func (m *TokenManager) refresh(channelID string) error {
+	channel, ok := m.config.Channel(channelID)
+	if !ok {
+		return fmt.Errorf("unknown channel: %s", channelID)
+	}
+
+	// send request to twitch for refresh token update
+	_, refreshToken := m.config.ChannelTokens(channel)
+	refreshResp, err := m.requestRefreshToken(refreshToken)
+	if err != nil {
+		return fmt.Errorf("refresh token: %s", err)
+	}
+
+	// keep the rotated refresh token
+	if len(refreshResp.RefreshToken) > 0 {
+		refreshToken = refreshResp.RefreshToken
+	}
+
+	// update and save config
+	if err := m.config.SetChannelTokens(channel, refreshResp.AccessToken, refreshToken); err != nil {
+		return fmt.Errorf("save config: %s", err)
+	}
+
+	log.Printf("[INFO] tokens: channel [%s]: token refreshed", channelID)
+
+	// notify subscribers
+	m.notify(&TokenChange{
+		ChannelID:  channelID,
+		OAuthToken: refreshResp.AccessToken,
+	})
+
+	return nil
+}
+
+// sends a token change to every subscriber
+func (m *TokenManager) notify(change *TokenChange) {
+	m.mu.Lock()
+	subscribers := make([]func(*TokenChange), len(m.subscribers))
+	copy(subscribers, m.subscribers)
+	m.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
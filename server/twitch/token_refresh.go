@@ -0,0 +1,156 @@
+package twitch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	tokenPath         = "/token"
+	tokenValidatePath = "/validate"
+)
+
+// RefreshTokenResp is a successful response from a refresh request.
+type RefreshTokenResp struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	ExpiresIn    int      `json:"expires_in"`
+	Scope        []string `json:"scope"`
+}
+
+// RefreshTokenErrorResp is an invalid response from a refresh request.
+type RefreshTokenErrorResp struct {
+	Error   string `json:"error"`
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// ValidateTokenResp is a successful response from a validate request.
+type ValidateTokenResp struct {
+	ClientID  string   `json:"client_id"`
+	Login     string   `json:"login"`
+	UserID    string   `json:"user_id"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expires_in"`
+}
+
+// request new access and refresh tokens using refresh token
+func (m *TokenManager) requestRefreshToken(refreshToken string) (*RefreshTokenResp, error) {
+	return m.requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {m.config.TwitchClientID},
+		"client_secret": {m.config.TwitchClientSecret},
+	})
+}
+
+// request access and refresh tokens for an authorization code
+func (m *TokenManager) requestAuthorizationCode(code string) (*RefreshTokenResp, error) {
+	return m.requestToken(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {m.config.TwitchRedirectURI},
+		"client_id":     {m.config.TwitchClientID},
+		"client_secret": {m.config.TwitchClientSecret},
+	})
+}
+
+// request tokens from the twitch token endpoint
+func (m *TokenManager) requestToken(params url.Values) (*RefreshTokenResp, error) {
+	// build url with query params
+	reqURL := strings.Join([]string{m.config.OAuthURL(), tokenPath, "?", params.Encode()}, "")
+
+	// create new request
+	req, err := http.NewRequestWithContext(m.ctx, "POST", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error generating request: %v", err)
+	}
+
+	// do post request
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error doing request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// check for expected status codes
+	if (resp.StatusCode != http.StatusOK) && (resp.StatusCode != http.StatusBadRequest) {
+		return nil, fmt.Errorf("invalid response code: %d", resp.StatusCode)
+	}
+
+	// read body
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading body: %v", err)
+	}
+
+	// error status code
+	if resp.StatusCode == http.StatusBadRequest {
+		var errorResp RefreshTokenErrorResp
+
+		// unmarshal data
+		if err := json.Unmarshal(data, &errorResp); err != nil {
+			return nil, fmt.Errorf("error unmarshalling bad request: %s", err)
+		}
+
+		// return response error
+		return nil, fmt.Errorf("error reading body: %s: %s", errorResp.Error, errorResp.Message)
+	}
+
+	// success status code
+	var successResp RefreshTokenResp
+
+	// unmarshal data
+	if err := json.Unmarshal(data, &successResp); err != nil {
+		return nil, fmt.Errorf("error unmarshalling successful request: %s", err)
+	}
+
+	return &successResp, nil
+}
+
+// request validation of an access token, returning nil
+// with no error if twitch no longer accepts the token
+func (m *TokenManager) requestValidateToken(accessToken string) (*ValidateTokenResp, error) {
+	// create new request
+	req, err := http.NewRequestWithContext(m.ctx, "GET", m.config.OAuthURL()+tokenValidatePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error generating request: %v", err)
+	}
+
+	req.Header.Add("Authorization", "OAuth "+accessToken)
+
+	// do get request
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error doing request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// token is invalid or expired
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, nil
+	}
+
+	// check for expected status code
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid response code: %d", resp.StatusCode)
+	}
+
+	// read body
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading body: %v", err)
+	}
+
+	// unmarshal data
+	var validateResp ValidateTokenResp
+	if err := json.Unmarshal(data, &validateResp); err != nil {
+		return nil, fmt.Errorf("error unmarshalling successful request: %s", err)
+	}
+
+	return &validateResp, nil
+}
@@ -1,16 +1,22 @@
 package twitch
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
 	"time"
 
+	"github.com/codephobia/twitch-eos-thanks/helix"
 	"github.com/codephobia/twitch-eos-thanks/server/config"
 	"github.com/codephobia/twitch-eos-thanks/server/database"
+	"github.com/codephobia/twitch-eos-thanks/server/events"
+	"github.com/codephobia/twitch-eos-thanks/server/metrics"
 )
 
 var (
-	TWITCH_API_DELAY          time.Duration = 500 * time.Millisecond
-	TWITCH_API_FOLLOWER_LIMIT int           = 100
-	TWITCH_API_USER_LIMIT     int           = 100
+	TWITCH_API_FOLLOWER_LIMIT int = 100
+	TWITCH_API_USER_LIMIT     int = 100
 
 	TWITCH_HELIX_USERS_URL     string = "/users?"
 	TWITCH_HELIX_FOLLOWERS_URL string = "/users/follows?to_id="
@@ -20,38 +26,205 @@ var (
 type Twitch struct {
 	config   *config.Config
 	database *database.Database
+	metrics  *metrics.Metrics
+	events   *events.Bus
 
-	pubsub *PUBSUB
+	helix     *helix.Client
+	tokens    *TokenManager
+	webhooks  *Webhooks
+	pubsub    *PUBSUB
+	eventsubs []*EventSub
+	backfill  *Backfill
+
+	// dead letters waiting for the database
+	deadLettersMu      sync.Mutex
+	unsavedDeadLetters []*database.DeadLetter
+
+	// users waiting to be resolved
+	users        *userQueue
+	usersStarted sync.Once
+
+	// set while replaying a dry run, which leaves helix alone
+	dryRun bool
+
+	// cancelled on close, stopping background work and requests
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-// NewTwitch returns a new twitch.
-func NewTwitch(c *config.Config, db *database.Database) *Twitch {
+// NewTwitch returns a new twitch, publishing the events it ingests
+// to the bus.
+func NewTwitch(c *config.Config, db *database.Database, m *metrics.Metrics, bus *events.Bus) *Twitch {
+	ctx, cancel := context.WithCancel(context.Background())
+	tokens := NewTokenManager(ctx, c)
+
+	// helix reads use an app access token, falling back to the
+	// configured token without a client secret to get one with
+	var helixTokens helix.TokenSource = &AppTokenSource{tokens: tokens}
+	if len(c.TwitchClientSecret) == 0 {
+		helixTokens = helix.StaticToken(c.TwitchOAuthToken)
+	}
+	helixClient := helix.NewClient(c.TwitchClientID, helixTokens)
+
 	twitch := &Twitch{
 		config:   c,
 		database: db,
+		metrics:  m,
+		events:   bus,
+
+		helix:    helixClient,
+		tokens:   tokens,
+		webhooks: NewWebhooks(ctx, c, db, helixClient),
+		users:    newUserQueue(),
+
+		ctx:    ctx,
+		cancel: cancel,
 	}
 
-	twitch.pubsub = NewPUBSUB(c, db, twitch)
+	// split channels by their transport
+	pubsubChannels := make([]*config.Channel, 0)
+	for _, channel := range c.ChannelList() {
+		switch channel.Transport {
+		case config.TransportEventSub:
+			twitch.eventsubs = append(twitch.eventsubs, NewEventSub(c, db, twitch, channel))
+		default:
+			pubsubChannels = append(pubsubChannels, channel)
+		}
+	}
+
+	twitch.pubsub = NewPUBSUB(c, db, twitch, pubsubChannels)
+
+	// without a database there's nothing to backfill in to
+	if db != nil {
+		twitch.backfill = NewBackfill(c, db, twitch)
+	}
 
 	return twitch
 }
 
 // Init initializes the twitch channels, getting followers if need be
 func (t *Twitch) Init() error {
-	// check if we have followers already
-	hasFollowers, err := t.database.HasFollowers(t.config.TwitchChannelID)
-	if err != nil {
-		return err
+	// channels with followers already, reconciled once we're up
+	reconcile := make([]string, 0)
+
+	for _, channel := range t.config.ChannelList() {
+		// make sure the channel transport is valid
+		if channel.Transport != config.TransportPubSub && channel.Transport != config.TransportEventSub {
+			return fmt.Errorf("invalid twitch transport for channel [%s]: %s", channel.ID, channel.Transport)
+		}
+
+		// check if we have followers already
+		hasFollowers, err := t.database.HasFollowers(channel.ID)
+		if err != nil {
+			return err
+		}
+
+		// if we don't have followers, get followers
+		if !hasFollowers {
+			// get followers from twitch
+			if err := t.getFollowers(t.ctx, channel.ID); err != nil {
+				return fmt.Errorf("channel [%s]: %s", channel.ID, err)
+			}
+		} else {
+			reconcile = append(reconcile, channel.ID)
+		}
 	}
 
-	// if we don't have followers, get followers
-	if !hasFollowers {
-		// get followers from twitch
-		if err := t.getFollowers(); err != nil {
+	// catch follows and unfollows missed while we were down
+	go func() {
+		for _, channelID := range reconcile {
+			if err := t.reconcileFollowers(t.ctx, channelID); err != nil {
+				log.Printf("[ERROR] followers: channel [%s]: reconcile: %s", channelID, err)
+			}
+		}
+
+		t.runFollowerReconcile()
+	}()
+
+	// save dead letters held while the database was down
+	go t.runDeadLetterFlush()
+
+	// resolve the users events come in from
+	t.startUserResolver()
+
+	// validate channel tokens and keep them fresh
+	t.tokens.Init()
+
+	// keep follow webhooks subscribed for pub sub channels
+	t.webhooks.Init()
+
+	// init eventsub for each eventsub channel
+	for _, eventsub := range t.eventsubs {
+		if err := eventsub.Init(); err != nil {
 			return err
 		}
 	}
 
-	// init pubsub
-	return t.pubsub.Init()
+	// init pubsub if any channels use it
+	if len(t.pubsub.channels) > 0 {
+		return t.pubsub.Init()
+	}
+
+	return nil
+}
+
+// Close stops the background work of twitch and cancels the requests
+// it has in flight.
+func (t *Twitch) Close() {
+	t.cancel()
+	t.tokens.Stop()
+
+	for _, eventsub := range t.eventsubs {
+		eventsub.Close()
+	}
+	t.pubsub.closeJournal()
+}
+
+// PubSubStatus reports the state of each pub sub connection.
+func (t *Twitch) PubSubStatus() []*PUBSUBConnStatus {
+	return t.pubsub.Connections()
+}
+
+// PubSubTopics reports the topics of the pub sub channels.
+func (t *Twitch) PubSubTopics(channelID string) ([]*PUBSUBChannelTopics, error) {
+	return t.pubsub.Topics(channelID)
+}
+
+// AddPubSubTopic listens for a topic on a pub sub channel.
+func (t *Twitch) AddPubSubTopic(channelID string, topic string) error {
+	return t.pubsub.AddTopic(channelID, PUBSUBTopic(topic))
+}
+
+// RemovePubSubTopic stops listening for a topic on a pub sub channel.
+func (t *Twitch) RemovePubSubTopic(channelID string, topic string) error {
+	return t.pubsub.RemoveTopic(channelID, PUBSUBTopic(topic))
+}
+
+// PubSubBrokenTopics reports the pub sub topics given up on.
+func (t *Twitch) PubSubBrokenTopics() []*PUBSUBBrokenTopic {
+	return t.pubsub.BrokenTopics()
+}
+
+// ReplayPubSub replays the pub sub messages journaled within the window.
+// A dry run doesn't resolve users, and events are only saved if the bus
+// saves them.
+func (t *Twitch) ReplayPubSub(from time.Time, to time.Time, dryRun bool) (int, error) {
+	t.dryRun = dryRun
+	defer func() { t.dryRun = false }()
+
+	if !dryRun {
+		t.startUserResolver()
+	}
+
+	return t.pubsub.Replay(from, to, dryRun)
+}
+
+// Metrics returns the server metrics.
+func (t *Twitch) Metrics() *metrics.Metrics {
+	return t.metrics
+}
+
+// Tokens returns the channel token manager.
+func (t *Twitch) Tokens() *TokenManager {
+	return t.tokens
 }
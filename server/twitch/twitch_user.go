@@ -2,6 +2,12 @@ package twitch
 
 type TwitchUser struct {
 	ID              string `json:"id"`
+	Login           string `json:"login"`
 	DisplayName     string `json:"display_name"`
 	ProfileImageUrl string `json:"profile_image_url"`
 }
+
+// UserResp is a Twitch response of users
+type UserResp struct {
+	Data []*TwitchUser `json:"data"`
+}
@@ -0,0 +1,211 @@
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+var (
+	// cached user profiles older than this are fetched again
+	userRefreshTTL = 24 * time.Hour
+	// most user ids waiting to be resolved
+	userQueueSize = 10000
+)
+
+// userQueue holds the user ids waiting to be resolved, each only once.
+type userQueue struct {
+	mu     sync.Mutex
+	ids    []string
+	queued map[string]bool
+
+	// signalled when ids are added
+	ready chan struct{}
+}
+
+// returns a new empty user queue
+func newUserQueue() *userQueue {
+	return &userQueue{
+		queued: make(map[string]bool),
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// adds ids that aren't queued yet, returning how many were dropped
+// with the queue full
+func (q *userQueue) push(ids []string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	dropped := 0
+	for _, id := range ids {
+		if len(id) == 0 || q.queued[id] {
+			continue
+		}
+		if len(q.ids) >= userQueueSize {
+			dropped++
+			continue
+		}
+
+		q.ids = append(q.ids, id)
+		q.queued[id] = true
+	}
+
+	// wake the resolver if it's waiting
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+
+	return dropped
+}
+
+// takes up to max ids off the front of the queue
+func (q *userQueue) take(max int) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n := len(q.ids)
+	if n > max {
+		n = max
+	}
+
+	ids := q.ids[:n:n]
+	q.ids = q.ids[n:]
+	for _, id := range ids {
+		delete(q.queued, id)
+	}
+
+	return ids
+}
+
+// ResolveUsers makes sure the user profiles for the ids are cached,
+// fetching missing and stale profiles from twitch in batches.
+func (t *Twitch) ResolveUsers(ctx context.Context, ids []string) error {
+	// skip empty and duplicate ids
+	seen := make(map[string]bool)
+	unique := make([]string, 0)
+	for _, id := range ids {
+		if len(id) == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	// get cached users
+	cached, err := t.database.GetUsers(unique)
+	if err != nil {
+		return err
+	}
+
+	// find users we need from twitch
+	stale := time.Now().Add(-userRefreshTTL)
+	fetch := make([]string, 0)
+	for _, id := range unique {
+		if user, ok := cached[id]; ok && user.UpdatedAt.After(stale) {
+			continue
+		}
+		fetch = append(fetch, id)
+	}
+
+	// get users from twitch in batches
+	for start := 0; start < len(fetch); start += TWITCH_API_USER_LIMIT {
+		end := start + TWITCH_API_USER_LIMIT
+		if end > len(fetch) {
+			end = len(fetch)
+		}
+
+		if err := t.fetchUsers(ctx, fetch[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ResolveUsersAsync queues user profiles to be resolved in the
+// background, in batches as big as a single twitch request allows.
+func (t *Twitch) ResolveUsersAsync(ids ...string) {
+	if t.dryRun {
+		return
+	}
+
+	if dropped := t.users.push(ids); dropped > 0 {
+		log.Printf("[ERROR] users: queue full, dropped %d users", dropped)
+	}
+}
+
+// starts resolving queued users, once
+func (t *Twitch) startUserResolver() {
+	t.usersStarted.Do(func() {
+		go t.runUserResolver()
+	})
+}
+
+// resolves queued users one batch at a time
+func (t *Twitch) runUserResolver() {
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-t.users.ready:
+		}
+
+		for {
+			ids := t.users.take(TWITCH_API_USER_LIMIT)
+			if len(ids) == 0 {
+				break
+			}
+
+			if err := t.ResolveUsers(t.ctx, ids); err != nil {
+				log.Printf("[ERROR] users: resolve: %s", err)
+			}
+		}
+	}
+}
+
+// gets a batch of users from twitch and caches them
+func (t *Twitch) fetchUsers(ctx context.Context, ids []string) error {
+	// build out url ids
+	urlIds := make([]string, 0, len(ids))
+	for _, id := range ids {
+		urlIds = append(urlIds, "id="+id)
+	}
+	url := strings.Join([]string{TWITCH_HELIX_USERS_URL, strings.Join(urlIds, "&")}, "")
+
+	// get user data from twitch
+	body, err := t.getTwitchResponse(ctx, TwitchHelix, url)
+	if err != nil {
+		return err
+	}
+
+	// decode body
+	userResp := &UserResp{}
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(userResp); err != nil {
+		return fmt.Errorf("body decode: %s", err)
+	}
+
+	// cache users
+	now := time.Now()
+	for _, user := range userResp.Data {
+		if err := t.database.SaveUser(&database.User{
+			ID:              user.ID,
+			Login:           user.Login,
+			DisplayName:     user.DisplayName,
+			ProfileImageURL: user.ProfileImageUrl,
+			UpdatedAt:       now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -7,11 +7,12 @@ const (
 	TwitchHelix
 )
 
-func (v TwitchVersion) Url() string {
-	return twitchVersionUrl[v]
+// Url returns the url of the api version under the base api url.
+func (v TwitchVersion) Url(apiURL string) string {
+	return apiURL + twitchVersionUrl[v]
 }
 
 var twitchVersionUrl = map[TwitchVersion]string{
-	TwitchV5:    "https://api.twitch.tv/kraken",
-	TwitchHelix: "https://api.twitch.tv/helix",
+	TwitchV5:    "/kraken",
+	TwitchHelix: "/helix",
 }
@@ -0,0 +1,207 @@
+package twitch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codephobia/twitch-eos-thanks/helix"
+	"github.com/codephobia/twitch-eos-thanks/server/config"
+	"github.com/codephobia/twitch-eos-thanks/server/database"
+)
+
+var (
+	TWITCH_HELIX_WEBHOOKS_HUB_URL string = "/webhooks/hub"
+
+	// longest lease twitch allows
+	webhookLeaseSeconds = 864000
+	// renew subscriptions this long before their lease runs out
+	webhookRenewBefore = 24 * time.Hour
+	// resubscribe if twitch hasn't verified a subscription in time
+	webhookVerifyTimeout = 10 * time.Minute
+	// how often subscription leases are checked
+	webhookCheckPeriod = 10 * time.Minute
+)
+
+// WebhookHubRequest is a subscription request to the twitch webhooks hub.
+type WebhookHubRequest struct {
+	Callback     string `json:"hub.callback"`
+	Mode         string `json:"hub.mode"`
+	Topic        string `json:"hub.topic"`
+	LeaseSeconds int    `json:"hub.lease_seconds"`
+	Secret       string `json:"hub.secret"`
+}
+
+// Webhooks keeps the follow webhook subscriptions for pub sub
+// channels alive, since pub sub has no follow topic.
+type Webhooks struct {
+	config   *config.Config
+	database *database.Database
+
+	helix *helix.Client
+
+	// cancelled when twitch closes
+	ctx context.Context
+}
+
+// NewWebhooks returns a new webhook subscription manager, which stops
+// once the context is done.
+func NewWebhooks(ctx context.Context, c *config.Config, db *database.Database, h *helix.Client) *Webhooks {
+	return &Webhooks{
+		config:   c,
+		database: db,
+
+		helix: h,
+
+		ctx: ctx,
+	}
+}
+
+// WebhookFollowTopic returns the follow webhook topic for a channel on
+// the api at apiURL.
+func WebhookFollowTopic(apiURL string, channelID string) string {
+	return strings.Join([]string{TwitchHelix.Url(apiURL), TWITCH_HELIX_FOLLOWERS_URL, channelID, "&first=1"}, "")
+}
+
+// Init subscribes to follow webhooks and starts renewing their leases.
+func (wh *Webhooks) Init() {
+	if len(wh.config.TwitchWebhookCallback) == 0 {
+		return
+	}
+
+	log.Printf("[INFO] webhooks: initializing")
+
+	// subscribe to any channel without a live subscription
+	wh.renew()
+
+	go wh.run()
+}
+
+// renews leases on the schedule until twitch closes
+func (wh *Webhooks) run() {
+	ticker := time.NewTicker(webhookCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-wh.ctx.Done():
+			return
+		case <-ticker.C:
+			wh.renew()
+		}
+	}
+}
+
+// subscribes every pub sub channel whose subscription is
+// missing, unverified or about to run out
+func (wh *Webhooks) renew() {
+	now := time.Now()
+
+	for _, channel := range wh.config.ChannelList() {
+		// eventsub channels get follows over eventsub
+		if channel.Transport != config.TransportPubSub {
+			continue
+		}
+
+		topic := WebhookFollowTopic(wh.config.APIURL(), channel.ID)
+
+		// get current subscription
+		webhook, err := wh.database.GetWebhook(topic)
+		if err != nil {
+			log.Printf("[ERROR] webhooks: channel [%s]: %s", channel.ID, err)
+			continue
+		}
+
+		if webhook != nil {
+			// waiting on twitch to verify
+			if !webhook.Verified && now.Sub(webhook.Timestamp) < webhookVerifyTimeout {
+				continue
+			}
+
+			// lease still has time left
+			if webhook.Verified && webhook.ExpiresAt.Sub(now) > webhookRenewBefore {
+				continue
+			}
+		}
+
+		if err := wh.subscribe(channel.ID, webhook); err != nil {
+			log.Printf("[ERROR] webhooks: channel [%s]: subscribe: %s", channel.ID, err)
+		}
+	}
+}
+
+// sends a subscribe request for the channel follow topic, keeping
+// the secret of the current subscription if there is one
+func (wh *Webhooks) subscribe(channelID string, current *database.Webhook) error {
+	log.Printf("[INFO] webhooks: channel [%s]: subscribing", channelID)
+
+	webhook := &database.Webhook{
+		ChannelID: channelID,
+		Topic:     WebhookFollowTopic(wh.config.APIURL(), channelID),
+		Timestamp: time.Now(),
+	}
+
+	// keep the lease of the current subscription until twitch verifies
+	if current != nil {
+		webhook.Secret = current.Secret
+		webhook.LeaseSeconds = current.LeaseSeconds
+		webhook.Verified = current.Verified
+		webhook.ExpiresAt = current.ExpiresAt
+	} else {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return fmt.Errorf("secret: %s", err)
+		}
+		webhook.Secret = secret
+	}
+
+	// save subscription so the verification request is recognized
+	if err := wh.database.SaveWebhook(webhook); err != nil {
+		return err
+	}
+
+	// marshal hub request
+	body, err := json.Marshal(&WebhookHubRequest{
+		Callback:     wh.config.TwitchWebhookCallback,
+		Mode:         "subscribe",
+		Topic:        webhook.Topic,
+		LeaseSeconds: webhookLeaseSeconds,
+		Secret:       webhook.Secret,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling hub request: %s", err)
+	}
+
+	// build url with version prefix / suffix
+	url := strings.Join([]string{TwitchHelix.Url(wh.config.APIURL()), TWITCH_HELIX_WEBHOOKS_HUB_URL}, "")
+
+	// do post request
+	header := http.Header{"Content-Type": {"application/json"}}
+	status, _, err := wh.helix.Do(wh.ctx, "POST", url, header, body)
+	if err != nil {
+		return err
+	}
+
+	// subscription was accepted, twitch verifies it next
+	if status != http.StatusAccepted {
+		return fmt.Errorf("invalid response code: %d", status)
+	}
+
+	return nil
+}
+
+// creates a new subscription secret
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}